@@ -12,13 +12,14 @@ import (
 )
 
 func main() {
-	if len(os.Args) == 2 {
+	if len(os.Args) >= 2 {
+		evaluator.SetArgs(os.Args[1], os.Args[2:])
 		data, err := ioutil.ReadFile(os.Args[1])
 		if err != nil {
 			print(err)
 			os.Exit(1)
 		}
-		env := evaluator.SharedEnv.NewEnclosedEnvironment()
+		env := evaluator.NewFileEnvironment(os.Args[1])
 		l := lexer.New(string(data))
 		p := parser.New(l)
 
@@ -35,10 +36,8 @@ func main() {
 			os.Exit(1)
 		}
 		os.Exit(0)
-	} else if len(os.Args) == 1 {
+	} else {
 		fmt.Printf("Welcome to T Language!\n")
 		repl.Start(os.Stdin, os.Stdout)
-	} else {
-		fmt.Printf("Usage: " + os.Args[0] + " <file>")
 	}
 }
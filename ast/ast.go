@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"github.com/mark07x/TLang/token"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -161,6 +162,45 @@ func (rs *DelStatement) String() string {
 	return out.String()
 }
 
+// ImportStatement is import "x.t" as m; or import { a, b as c } from "x.t";
+// Aliases has an entry for each of Names, nil when the name is not renamed.
+type ImportStatement struct {
+	Token   token.Token // the 'import' token
+	Path    *StringLiteral
+	Alias   *Identifier
+	Names   []*Identifier
+	Aliases []*Identifier
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	if is.Alias != nil {
+		out.WriteString(is.Path.String())
+		out.WriteString(" as ")
+		out.WriteString(is.Alias.String())
+	} else {
+		var names []string
+		for i, name := range is.Names {
+			if is.Aliases[i] != nil {
+				names = append(names, name.String()+" as "+is.Aliases[i].String())
+			} else {
+				names = append(names, name.String())
+			}
+		}
+		out.WriteString("{ ")
+		out.WriteString(strings.Join(names, ", "))
+		out.WriteString(" } from ")
+		out.WriteString(is.Path.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token // the token.Ident token
 	Value string
@@ -184,9 +224,11 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+// IntegerLiteral holds literals which do not fit in int64 in Big.
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
@@ -458,6 +500,33 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+type SliceExpression struct {
+	Token token.Token // The ':' token
+	Start Expression
+	End   Expression
+	Step  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+
+	return out.String()
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -501,6 +570,7 @@ func (al *ArrayLiteral) String() string {
 type HashLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Keys  []Expression // the keys of Pairs in order
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -509,8 +579,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{ ")
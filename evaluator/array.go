@@ -0,0 +1,365 @@
+package evaluator
+
+import "sort"
+
+func arrayArg(name string, args []Object, i int) (*Array, Object) {
+	if i < len(args) {
+		if arr, ok := UnwrapReferenceValue(args[i]).(*Array); ok {
+			return arr, nil
+		}
+	}
+	return nil, newError("native function %s: args[%d] should be Array", name, i)
+}
+
+func functorArg(name string, args []Object, i int) (Functor, Object) {
+	if i < len(args) {
+		if fn, ok := UnwrapReferenceValue(args[i]).(Functor); ok {
+			return fn, nil
+		}
+	}
+	return nil, newError("native function %s: args[%d] should be Functor", name, i)
+}
+
+func callFunctor(fn Functor, args []Object, env *Environment) Object {
+	return UnwrapReferenceValue(applyCall(fn, args, env))
+}
+
+func elementsOf(arr *Array) []Object {
+	elements := make([]Object, len(arr.Elements))
+	for i, e := range arr.Elements {
+		elements[i] = UnwrapReferenceValue(e).Copy()
+	}
+	return elements
+}
+
+func newArrayNative(name string, min, max int, fn func(arr *Array, args []Object, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function %s: len(args) should be %d", name, min)
+			}
+			return newError("native function %s: len(args) should be %d to %d", name, min, max)
+		}
+		arr, err := arrayArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return fn(arr, args, env)
+	}}
+}
+
+// mutableArrayArg returns args[0] as an Array that can be modified in place,
+// which needs a Reference that is not const.
+func mutableArrayArg(name string, args []Object) (*Array, Object) {
+	arr, err := arrayArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if refer, ok := args[0].(*Reference); !ok || refer.Const {
+		return nil, newError("native function %s: assign to const reference", name)
+	}
+	return arr, nil
+}
+
+func newMutableArrayNative(name string, min, max int, fn func(arr *Array, args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			if max < 0 {
+				return newError("native function %s: len(args) should be at least %d", name, min)
+			}
+			if min == max {
+				return newError("native function %s: len(args) should be %d", name, min)
+			}
+			return newError("native function %s: len(args) should be %d to %d", name, min, max)
+		}
+		arr, err := mutableArrayArg(name, args)
+		if err != nil {
+			return err
+		}
+		return fn(arr, args)
+	}}
+}
+
+func indexArg(name string, args []Object, i int, length int64) (int64, Object) {
+	index, err := integerArg(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= length {
+		return 0, newError("native function %s: out of range", name)
+	}
+	return index, nil
+}
+
+// predicate calls fn with e, or tests e itself when fn is nil.
+func predicate(fn Functor, e Object, env *Environment) (bool, Object) {
+	if fn == nil {
+		return isTruthy(e), nil
+	}
+	result := callFunctor(fn, []Object{e}, env)
+	if isError(result) {
+		return false, result
+	}
+	return isTruthy(result), nil
+}
+
+func flattenElements(elements []Object, depth int64) []Object {
+	var flat []Object
+	for _, e := range elements {
+		e = UnwrapReferenceValue(e)
+		if arr, ok := e.(*Array); ok && depth != 0 {
+			flat = append(flat, flattenElements(arr.Elements, depth-1)...)
+		} else {
+			flat = append(flat, e.Copy())
+		}
+	}
+	return flat
+}
+
+// arrayNatives are registered in SharedEnv, and can be called as methods of
+// Array, e.g. [1, 2].map(f) is map([1, 2], f).
+var arrayNatives map[string]*Native
+
+func newArrayNatives() map[string]*Native {
+	return map[string]*Native{
+		"map": newArrayNative("map", 2, 2, func(arr *Array, args []Object, env *Environment) Object {
+			fn, err := functorArg("map", args, 1)
+			if err != nil {
+				return err
+			}
+			elements := make([]Object, 0, len(arr.Elements))
+			for _, e := range elementsOf(arr) {
+				result := callFunctor(fn, []Object{e}, env)
+				if isError(result) {
+					return result
+				}
+				elements = append(elements, result.Copy())
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"filter": newArrayNative("filter", 2, 2, func(arr *Array, args []Object, env *Environment) Object {
+			fn, err := functorArg("filter", args, 1)
+			if err != nil {
+				return err
+			}
+			elements := []Object{}
+			for _, e := range elementsOf(arr) {
+				ok, err := predicate(fn, e, env)
+				if err != nil {
+					return err
+				}
+				if ok {
+					elements = append(elements, e)
+				}
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"reduce": newArrayNative("reduce", 2, 3, func(arr *Array, args []Object, env *Environment) Object {
+			fn, err := functorArg("reduce", args, 1)
+			if err != nil {
+				return err
+			}
+			elements := elementsOf(arr)
+			var acc Object
+			if len(args) == 3 {
+				acc = UnwrapReferenceValue(args[2])
+			} else {
+				if len(elements) == 0 {
+					return newError("native function reduce: empty Array with no initial value")
+				}
+				acc, elements = elements[0], elements[1:]
+			}
+			for _, e := range elements {
+				acc = callFunctor(fn, []Object{acc, e}, env)
+				if isError(acc) {
+					return acc
+				}
+			}
+			return acc
+		}),
+		"find": newArrayNative("find", 2, 2, func(arr *Array, args []Object, env *Environment) Object {
+			fn, err := functorArg("find", args, 1)
+			if err != nil {
+				return err
+			}
+			for _, e := range elementsOf(arr) {
+				ok, err := predicate(fn, e, env)
+				if err != nil {
+					return err
+				}
+				if ok {
+					return e
+				}
+			}
+			return VoidObj
+		}),
+		"any": newArrayNative("any", 1, 2, func(arr *Array, args []Object, env *Environment) Object {
+			var fn Functor
+			if len(args) == 2 {
+				var err Object
+				if fn, err = functorArg("any", args, 1); err != nil {
+					return err
+				}
+			}
+			for _, e := range elementsOf(arr) {
+				ok, err := predicate(fn, e, env)
+				if err != nil {
+					return err
+				}
+				if ok {
+					return TrueObj
+				}
+			}
+			return FalseObj
+		}),
+		"all": newArrayNative("all", 1, 2, func(arr *Array, args []Object, env *Environment) Object {
+			var fn Functor
+			if len(args) == 2 {
+				var err Object
+				if fn, err = functorArg("all", args, 1); err != nil {
+					return err
+				}
+			}
+			for _, e := range elementsOf(arr) {
+				ok, err := predicate(fn, e, env)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return FalseObj
+				}
+			}
+			return TrueObj
+		}),
+		"zip": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 0 {
+				return newError("native function zip: len(args) should be at least 1")
+			}
+			var arrs [][]Object
+			length := -1
+			for i := range args {
+				arr, err := arrayArg("zip", args, i)
+				if err != nil {
+					return err
+				}
+				arrs = append(arrs, elementsOf(arr))
+				if length < 0 || len(arr.Elements) < length {
+					length = len(arr.Elements)
+				}
+			}
+			elements := make([]Object, length)
+			for i := range elements {
+				row := make([]Object, len(arrs))
+				for j, arr := range arrs {
+					row[j] = arr[i]
+				}
+				elements[i] = &Array{Elements: row, Xvalue: true}
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}},
+		"enumerate": newArrayNative("enumerate", 1, 1, func(arr *Array, args []Object, env *Environment) Object {
+			elements := elementsOf(arr)
+			for i, e := range elements {
+				elements[i] = &Array{Elements: []Object{&Integer{Value: int64(i)}, e}, Xvalue: true}
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"reverse": newArrayNative("reverse", 1, 1, func(arr *Array, args []Object, env *Environment) Object {
+			elements := elementsOf(arr)
+			for i, j := 0, len(elements)-1; i < j; i, j = i+1, j-1 {
+				elements[i], elements[j] = elements[j], elements[i]
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"flatten": newArrayNative("flatten", 1, 2, func(arr *Array, args []Object, env *Environment) Object {
+			depth := int64(1)
+			if len(args) == 2 {
+				var err Object
+				if depth, err = integerArg("flatten", args, 1); err != nil {
+					return err
+				}
+			}
+			return &Array{Elements: flattenElements(arr.Elements, depth), Xvalue: true}
+		}),
+		"push": newMutableArrayNative("push", 1, -1, func(arr *Array, args []Object) Object {
+			for _, e := range args[1:] {
+				arr.Elements = append(arr.Elements, UnwrapReferenceValue(e).Copy())
+			}
+			return VoidObj
+		}),
+		"insert": newMutableArrayNative("insert", 3, 3, func(arr *Array, args []Object) Object {
+			index, err := indexArg("insert", args, 1, int64(len(arr.Elements))+1)
+			if err != nil {
+				return err
+			}
+			arr.Elements = append(arr.Elements, nil)
+			copy(arr.Elements[index+1:], arr.Elements[index:])
+			arr.Elements[index] = UnwrapReferenceValue(args[2]).Copy()
+			return VoidObj
+		}),
+		"remove": newMutableArrayNative("remove", 2, 2, func(arr *Array, args []Object) Object {
+			index, err := indexArg("remove", args, 1, int64(len(arr.Elements)))
+			if err != nil {
+				return err
+			}
+			removed := UnwrapReferenceValue(arr.Elements[index])
+			arr.Elements = append(arr.Elements[:index:index], arr.Elements[index+1:]...)
+			return removed
+		}),
+		"pop": newMutableArrayNative("pop", 1, 1, func(arr *Array, args []Object) Object {
+			if len(arr.Elements) == 0 {
+				return newError("native function pop: empty Array")
+			}
+			last := UnwrapReferenceValue(arr.Elements[len(arr.Elements)-1])
+			arr.Elements = arr.Elements[:len(arr.Elements)-1]
+			return last
+		}),
+		"clear": newMutableArrayNative("clear", 1, 1, func(arr *Array, args []Object) Object {
+			arr.Elements = []Object{}
+			return VoidObj
+		}),
+		"sort": newArrayNative("sort", 1, 2, func(arr *Array, args []Object, env *Environment) Object {
+			var fn Functor
+			if len(args) == 2 {
+				var err Object
+				if fn, err = functorArg("sort", args, 1); err != nil {
+					return err
+				}
+			}
+			elements := elementsOf(arr)
+			var err Object
+			sort.SliceStable(elements, func(i, j int) bool {
+				if err != nil {
+					return false
+				}
+				var less Object
+				if fn == nil {
+					less = evalInfixExpression("<", elements[i], elements[j], env)
+				} else {
+					less = callFunctor(fn, []Object{elements[i], elements[j]}, env)
+				}
+				if isError(less) {
+					err = less
+					return false
+				}
+				return isTruthy(less)
+			})
+			if err != nil {
+				return err
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+	}
+}
+
+// overloadNative calls arrayFn when args[0] is an Array, and otherwise fn.
+func overloadNative(arrayFn, fn *Native) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) > 0 {
+			if _, ok := UnwrapReferenceValue(args[0]).(*Array); ok {
+				return arrayFn.Fn(env, args)
+			}
+		}
+		return fn.Fn(env, args)
+	}}
+}
@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"strings"
+)
+
+func newCodecNative(name string, n int, fn func(args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != n {
+			return newError("native function %s: len(args) should be %d", name, n)
+		}
+		return fn(args)
+	}}
+}
+
+func newEncodeNative(name string, encode func(data []byte) string) *Native {
+	return newCodecNative(name, 1, func(args []Object) Object {
+		data, err := dataArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return &String{Value: []rune(encode(data))}
+	})
+}
+
+func newDecodeNative(name string, decode func(str string) ([]byte, error)) *Native {
+	return newCodecNative(name, 1, func(args []Object) Object {
+		str, err := stringArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		data, e := decode(string(str))
+		if e != nil {
+			return newError("native function %s: %s", name, e.Error())
+		}
+		return &Bytes{Value: data}
+	})
+}
+
+// base64Decoder accepts data with or without the padding.
+func base64Decoder(encoding *base64.Encoding) func(str string) ([]byte, error) {
+	return func(str string) ([]byte, error) {
+		return encoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(str, "="))
+	}
+}
+
+func newBase64Module() *Hash {
+	return newModule(map[string]Object{
+		"encode":    newEncodeNative("base64.encode", base64.StdEncoding.EncodeToString),
+		"decode":    newDecodeNative("base64.decode", base64Decoder(base64.StdEncoding)),
+		"urlEncode": newEncodeNative("base64.urlEncode", base64.URLEncoding.EncodeToString),
+		"urlDecode": newDecodeNative("base64.urlDecode", base64Decoder(base64.URLEncoding)),
+	})
+}
+
+func newHexModule() *Hash {
+	return newModule(map[string]Object{
+		"encode": newEncodeNative("hex.encode", hex.EncodeToString),
+		"decode": newDecodeNative("hex.decode", hex.DecodeString),
+	})
+}
+
+// digests are the hash functions of digest.hmac by name.
+var digests = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+func newDigestNative(name string, newHash func() hash.Hash) *Native {
+	return newEncodeNative("digest."+name, func(data []byte) string {
+		h := newHash()
+		h.Write(data)
+		return hex.EncodeToString(h.Sum(nil))
+	})
+}
+
+func newDigestModule() *Hash {
+	members := map[string]Object{
+		"crc32": newCodecNative("digest.crc32", 1, func(args []Object) Object {
+			data, err := dataArg("digest.crc32", args, 0)
+			if err != nil {
+				return err
+			}
+			return &Integer{Value: int64(crc32.ChecksumIEEE(data))}
+		}),
+		// digest.hmac("sha256", key, data)
+		"hmac": newCodecNative("digest.hmac", 3, func(args []Object) Object {
+			algorithm, err := stringArg("digest.hmac", args, 0)
+			if err != nil {
+				return err
+			}
+			newHash, ok := digests[string(algorithm)]
+			if !ok {
+				return newError("native function digest.hmac: unknown hash function %s", string(algorithm))
+			}
+			key, err := dataArg("digest.hmac", args, 1)
+			if err != nil {
+				return err
+			}
+			data, err := dataArg("digest.hmac", args, 2)
+			if err != nil {
+				return err
+			}
+			mac := hmac.New(newHash, key)
+			mac.Write(data)
+			return &String{Value: []rune(hex.EncodeToString(mac.Sum(nil)))}
+		}),
+	}
+	for name, newHash := range digests {
+		members[name] = newDigestNative(name, newHash)
+	}
+	return newModule(members)
+}
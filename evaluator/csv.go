@@ -0,0 +1,360 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// csvConfig holds the options of the csv natives: "delimiter", "comment",
+// "header", "lazyQuotes" and "trim" for reading, "delimiter", "quoteAll" and
+// "crlf" for writing.
+type csvConfig struct {
+	delimiter  rune
+	comment    rune
+	header     bool
+	lazyQuotes bool
+	trim       bool
+	quoteAll   bool
+	crlf       bool
+}
+
+func csvOptions(name string, args []Object, i int) (*csvConfig, Object) {
+	cfg := &csvConfig{delimiter: ','}
+	if i >= len(args) {
+		return cfg, nil
+	}
+	options, err := hashArg(name, args, i)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range ownPairs(options) {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return nil, newError("native function %s: unknown option %s", name, pair.Key.Inspect(16, nil))
+		}
+		value := UnwrapReferenceValue(pairValue(pair))
+		option := string(key.Value)
+		switch option {
+		case "delimiter", "comment":
+			str, err := stringArg(name, []Object{value}, 0)
+			if err != nil || len(str) != 1 {
+				return nil, newError("native function %s: %s should be a single character", name, option)
+			}
+			if option == "delimiter" {
+				cfg.delimiter = str[0]
+			} else {
+				cfg.comment = str[0]
+			}
+		case "header", "lazyQuotes", "trim", "quoteAll", "crlf":
+			b, ok := value.(*Boolean)
+			if !ok {
+				return nil, newError("native function %s: %s should be Boolean", name, option)
+			}
+			switch option {
+			case "header":
+				cfg.header = b.Value
+			case "lazyQuotes":
+				cfg.lazyQuotes = b.Value
+			case "trim":
+				cfg.trim = b.Value
+			case "quoteAll":
+				cfg.quoteAll = b.Value
+			case "crlf":
+				cfg.crlf = b.Value
+			}
+		default:
+			return nil, newError("native function %s: unknown option %s", name, option)
+		}
+	}
+	return cfg, nil
+}
+
+func (cfg *csvConfig) reader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = cfg.delimiter
+	reader.Comment = cfg.comment
+	reader.LazyQuotes = cfg.lazyQuotes
+	reader.TrimLeadingSpace = cfg.trim
+	if !cfg.header {
+		reader.FieldsPerRecord = -1
+	}
+	return reader
+}
+
+// csvRow makes a record into an Array, or into a Hash with the keys in
+// header when there is one.
+func csvRow(record []string, header []string) Object {
+	if header == nil {
+		return stringsToArray(record)
+	}
+	row := &Hash{Pairs: make(map[HashKey]HashPair, len(header))}
+	for i, name := range header {
+		setMember(row, name, &String{Value: []rune(record[i])})
+	}
+	return row
+}
+
+func readCsv(name string, r io.Reader, cfg *csvConfig) Object {
+	records, e := cfg.reader(r).ReadAll()
+	if e != nil {
+		return newError("native function %s: %s", name, e.Error())
+	}
+	var header []string
+	if cfg.header && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+	rows := make([]Object, len(records))
+	for i, record := range records {
+		rows[i] = csvRow(record, header)
+	}
+	return &Array{Elements: rows, Xvalue: true}
+}
+
+func csvField(field string, cfg *csvConfig) string {
+	if !cfg.quoteAll && field != "" && field[0] != ' ' && field[0] != '\t' &&
+		!strings.ContainsAny(field, string(cfg.delimiter)+"\"\r\n") {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+// formatCsv writes rows of Arrays or Tuples, or rows of Hashes, which are
+// written with a header row of the keys of the first Hash.
+func formatCsv(name string, rows *Array, cfg *csvConfig, env *Environment) ([]byte, Object) {
+	var buf bytes.Buffer
+	newline := "\n"
+	if cfg.crlf {
+		newline = "\r\n"
+	}
+	writeRecord := func(fields []Object) Object {
+		for i, field := range fields {
+			if i > 0 {
+				buf.WriteRune(cfg.delimiter)
+			}
+			field = UnwrapReferenceValue(field)
+			if field == VoidObj {
+				continue
+			}
+			str, ok := toString(field, env).(*String)
+			if !ok {
+				return newError("native function %s: field should be String", name)
+			}
+			buf.WriteString(csvField(string(str.Value), cfg))
+		}
+		buf.WriteString(newline)
+		return nil
+	}
+
+	// the header has the keys of all the Hash rows, in the order they first
+	// appear, so no field is lost when rows have different keys
+	var header []Object
+	hashes := false
+	if len(rows.Elements) > 0 {
+		if _, hashes = UnwrapReferenceValue(rows.Elements[0]).(*Hash); hashes {
+			seen := map[HashKey]bool{}
+			for _, row := range rows.Elements {
+				hash, ok := UnwrapReferenceValue(row).(*Hash)
+				if !ok {
+					continue
+				}
+				for _, pair := range ownPairs(hash) {
+					if key := pair.Key.(HashAble).HashKey(); !seen[key] {
+						seen[key] = true
+						header = append(header, pair.Key)
+					}
+				}
+			}
+			if err := writeRecord(header); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i, row := range rows.Elements {
+		var fields []Object
+		row = UnwrapReferenceValue(row)
+		switch row := row.(type) {
+		case *Array:
+			fields = row.Elements
+		case *Tuple:
+			fields = row.Elements
+		case *Hash:
+			for _, key := range header {
+				value := VoidObj
+				if pair, ok := row.Pairs[key.(HashAble).HashKey()]; ok {
+					value = pairValue(pair)
+				}
+				fields = append(fields, value)
+			}
+		default:
+			return nil, newError("native function %s: rows[%d] should be Array or Hash", name, i)
+		}
+		if _, ok := row.(*Hash); ok != hashes {
+			return nil, newError("native function %s: rows should all be Arrays or all be Hashes", name)
+		}
+		if err := writeRecord(fields); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func newCsvReaderNative(name string, fn func(r *CsvReader, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function csvReader.%s: len(args) should be 1", name)
+		}
+		r, ok := UnwrapReferenceValue(args[0]).(*CsvReader)
+		if !ok {
+			return newError("native function csvReader.%s: args[0] should be CsvReader", name)
+		}
+		return fn(r, env)
+	}}
+}
+
+// Next reads the next row, and closes the file after the last one.
+func (r *CsvReader) Next(env *Environment) (Object, bool) {
+	if r.File == nil {
+		return nil, false
+	}
+	reader := r.Reader
+	var record []string
+	var e error
+	blocking(func() { record, e = reader.Read() })
+	if e == nil && r.UseHeader && r.Header == nil {
+		r.Header = record
+		blocking(func() { record, e = reader.Read() })
+	}
+	if e != nil {
+		_ = r.File.Close()
+		r.File = nil
+		if e == io.EOF {
+			return nil, false
+		}
+		return newError("native function csvReader.next: %s", e.Error()), true
+	}
+	return csvRow(record, r.Header), true
+}
+
+// csvReaderMethods are the methods of CsvReader, which is args[0].
+var csvReaderMethods = map[string]*Native{
+	"next": newCsvReaderNative("next", func(r *CsvReader, env *Environment) Object {
+		row, ok := r.Next(env)
+		if !ok {
+			return VoidObj
+		}
+		return row
+	}),
+	"close": newCsvReaderNative("close", func(r *CsvReader, env *Environment) Object {
+		if r.File != nil {
+			e := r.File.Close()
+			r.File = nil
+			if e != nil {
+				return fsError("csvReader.close", e)
+			}
+		}
+		return VoidObj
+	}),
+}
+
+func newCsvNative(name string, min, max int, fn func(args []Object, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			return newError("native function csv.%s: len(args) should be %d to %d", name, min, max)
+		}
+		return fn(args, env)
+	}}
+}
+
+func newCsvModule() *Hash {
+	return newModule(map[string]Object{
+		"parse": newCsvNative("parse", 1, 2, func(args []Object, env *Environment) Object {
+			str, err := stringArg("csv.parse", args, 0)
+			if err != nil {
+				return err
+			}
+			cfg, err := csvOptions("csv.parse", args, 1)
+			if err != nil {
+				return err
+			}
+			return readCsv("csv.parse", strings.NewReader(string(str)), cfg)
+		}),
+		"read": newCsvNative("read", 1, 2, func(args []Object, env *Environment) Object {
+			path, err := pathArg("csv.read", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			cfg, err := csvOptions("csv.read", args, 1)
+			if err != nil {
+				return err
+			}
+			var data []byte
+			var e error
+			blocking(func() { data, e = ioutil.ReadFile(path) })
+			if e != nil {
+				return fsError("csv.read", e)
+			}
+			return readCsv("csv.read", bytes.NewReader(data), cfg)
+		}),
+		// csv.reader(path) reads a file row by row, in loop row in (...)
+		"reader": newCsvNative("reader", 1, 2, func(args []Object, env *Environment) Object {
+			path, err := pathArg("csv.reader", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			cfg, err := csvOptions("csv.reader", args, 1)
+			if err != nil {
+				return err
+			}
+			var f *os.File
+			var e error
+			blocking(func() { f, e = os.Open(path) })
+			if e != nil {
+				return fsError("csv.reader", e)
+			}
+			return &CsvReader{Reader: cfg.reader(f), File: f, Path: path, UseHeader: cfg.header}
+		}),
+		"format": newCsvNative("format", 1, 2, func(args []Object, env *Environment) Object {
+			rows, err := arrayArg("csv.format", args, 0)
+			if err != nil {
+				return err
+			}
+			cfg, err := csvOptions("csv.format", args, 1)
+			if err != nil {
+				return err
+			}
+			data, err := formatCsv("csv.format", rows, cfg, env)
+			if err != nil {
+				return err
+			}
+			return &String{Value: []rune(string(data))}
+		}),
+		"write": newCsvNative("write", 2, 3, func(args []Object, env *Environment) Object {
+			path, err := pathArg("csv.write", CapWrite, args, 0)
+			if err != nil {
+				return err
+			}
+			rows, err := arrayArg("csv.write", args, 1)
+			if err != nil {
+				return err
+			}
+			cfg, err := csvOptions("csv.write", args, 2)
+			if err != nil {
+				return err
+			}
+			data, err := formatCsv("csv.write", rows, cfg, env)
+			if err != nil {
+				return err
+			}
+			var e error
+			blocking(func() { e = ioutil.WriteFile(path, data, 0644) })
+			if e != nil {
+				return fsError("csv.write", e)
+			}
+			return VoidObj
+		}),
+	})
+}
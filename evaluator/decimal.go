@@ -0,0 +1,206 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var roundingModes = map[string]bool{
+	"halfEven": true,
+	"halfUp":   true,
+	"halfDown": true,
+	"up":       true,
+	"down":     true,
+	"ceiling":  true,
+	"floor":    true,
+}
+
+// DecimalContext has the scale and rounding mode used when a division can not
+// be represented exactly. Each script and module has its own, which can be
+// changed by the native decimalContext.
+type DecimalContext struct {
+	Scale    int32
+	Rounding string
+}
+
+func newDecimalContext() *DecimalContext {
+	return &DecimalContext{Scale: 16, Rounding: "halfEven"}
+}
+
+// maxDecimalScale bounds the scales given to decimal and decimalContext, and
+// the exponents of parsed decimals, which take time and memory of their size.
+const maxDecimalScale = 10000
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func parseDecimal(str string) (*Decimal, Object) {
+	mantissa, exponent := strings.ToLower(str), int64(0)
+	if i := strings.IndexByte(mantissa, 'e'); i >= 0 {
+		var err error
+		if exponent, err = strconv.ParseInt(mantissa[i+1:], 10, 32); err != nil {
+			return nil, newError("could not parse %s as decimal", str)
+		}
+		mantissa = mantissa[:i]
+	}
+	scale := int64(0)
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = int64(len(mantissa) - i - 1)
+	}
+	scale -= exponent
+	if exponent > maxDecimalScale || exponent < -maxDecimalScale || scale > maxDecimalScale {
+		return nil, newError("could not parse %s as decimal: exponent out of range", str)
+	}
+	if scale < 0 {
+		scale = 0
+	}
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, newError("could not parse %s as decimal", str)
+	}
+	return roundDecimal(r, int32(scale), "down"), nil
+}
+
+// scaleArg checks that args[i] is a scale from 0 to maxDecimalScale.
+func scaleArg(name string, args []Object, i int) (int32, Object) {
+	scale, ok := UnwrapReferenceValue(args[i]).(*Integer)
+	if !ok || scale.Big != nil || scale.Value < 0 || scale.Value > maxDecimalScale {
+		return 0, newError("native function %s: args[%d] should be Integer from 0 to %d", name, i, maxDecimalScale)
+	}
+	return int32(scale.Value), nil
+}
+
+func toDecimal(obj Object) (*Decimal, Object) {
+	switch obj := obj.(type) {
+	case *Decimal:
+		return obj, nil
+	case *Integer:
+		return &Decimal{Value: obj.BigInt(), Scale: 0}, nil
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, newError("could not convert %s to decimal", obj.Inspect(16, nil))
+		}
+		return parseDecimal(strconv.FormatFloat(obj.Value, 'g', -1, 64))
+	case *String:
+		return parseDecimal(strings.TrimSpace(string(obj.Value)))
+	default:
+		return nil, newError("could not convert %s to decimal", obj.Type())
+	}
+}
+
+// roundDecimal rounds r to scale digits after the decimal point.
+func roundDecimal(r *big.Rat, scale int32, mode string) *Decimal {
+	num := new(big.Int).Mul(r.Num(), pow10(scale))
+	den := r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return &Decimal{Value: quo, Scale: scale}
+	}
+
+	sign := int64(num.Sign())
+	half := new(big.Int).Abs(rem)
+	half.Mul(half, big.NewInt(2))
+	cmpHalf := half.Cmp(den)
+
+	away := false
+	switch mode {
+	case "up":
+		away = true
+	case "down":
+		away = false
+	case "ceiling":
+		away = sign > 0
+	case "floor":
+		away = sign < 0
+	case "halfUp":
+		away = cmpHalf >= 0
+	case "halfDown":
+		away = cmpHalf > 0
+	default:
+		away = cmpHalf > 0 || (cmpHalf == 0 && quo.Bit(0) == 1)
+	}
+	if away {
+		quo.Add(quo, big.NewInt(sign))
+	}
+	return &Decimal{Value: quo, Scale: scale}
+}
+
+func trimDecimal(d *Decimal, minScale int32) *Decimal {
+	value, scale := new(big.Int).Set(d.Value), d.Scale
+	ten, rem := big.NewInt(10), new(big.Int)
+	for scale > minScale {
+		quo, _ := new(big.Int).QuoRem(value, ten, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		value, scale = quo, scale-1
+	}
+	return &Decimal{Value: value, Scale: scale}
+}
+
+func alignDecimal(left, right *Decimal) (*big.Int, *big.Int, int32) {
+	scale := left.Scale
+	if right.Scale > scale {
+		scale = right.Scale
+	}
+	leftVal := new(big.Int).Mul(left.Value, pow10(scale-left.Scale))
+	rightVal := new(big.Int).Mul(right.Value, pow10(scale-right.Scale))
+	return leftVal, rightVal, scale
+}
+
+func evalDecimalInfixExpression(
+	operator string,
+	left, right Object,
+	env *Environment,
+) Object {
+	leftDec, err := toDecimal(left)
+	if err != nil {
+		return err
+	}
+	rightDec, err := toDecimal(right)
+	if err != nil {
+		return err
+	}
+	leftVal, rightVal, scale := alignDecimal(leftDec, rightDec)
+
+	switch operator {
+	case "+":
+		return &Decimal{Value: new(big.Int).Add(leftVal, rightVal), Scale: scale}
+	case "-":
+		return &Decimal{Value: new(big.Int).Sub(leftVal, rightVal), Scale: scale}
+	case "*":
+		return &Decimal{Value: new(big.Int).Mul(leftDec.Value, rightDec.Value), Scale: leftDec.Scale + rightDec.Scale}
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero: %s / 0", left.Inspect(16, nil))
+		}
+		quo := new(big.Rat).Quo(leftDec.Rat(), rightDec.Rat())
+		ctx := env.DecimalContext()
+		return trimDecimal(roundDecimal(quo, ctx.Scale, ctx.Rounding), 0)
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero: %s %% 0", left.Inspect(16, nil))
+		}
+		return &Decimal{Value: new(big.Int).Rem(leftVal, rightVal), Scale: scale}
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
@@ -0,0 +1,91 @@
+package evaluator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxBytesLen bounds the length of the Bytes made by bytes(n).
+const maxBytesLen = 1 << 30
+
+func normalizeEncoding(encoding string) string {
+	return strings.Replace(strings.ToLower(encoding), "_", "-", -1)
+}
+
+func encodeString(str []rune, encoding string) ([]byte, error) {
+	switch normalizeEncoding(encoding) {
+	case "utf-8", "utf8":
+		return []byte(string(str)), nil
+	case "utf-16le", "utf-16be":
+		units := utf16.Encode(str)
+		data := make([]byte, 2*len(units))
+		for i, u := range units {
+			if normalizeEncoding(encoding) == "utf-16le" {
+				binary.LittleEndian.PutUint16(data[2*i:], u)
+			} else {
+				binary.BigEndian.PutUint16(data[2*i:], u)
+			}
+		}
+		return data, nil
+	case "latin-1", "latin1", "iso-8859-1":
+		return encodeSingleByte(str, 0xff, encoding)
+	case "ascii":
+		return encodeSingleByte(str, 0x7f, encoding)
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", encoding)
+	}
+}
+
+func encodeSingleByte(str []rune, max rune, encoding string) ([]byte, error) {
+	data := make([]byte, len(str))
+	for i, r := range str {
+		if r < 0 || r > max {
+			return nil, fmt.Errorf("character %q at %d can not be encoded in %s", r, i, encoding)
+		}
+		data[i] = byte(r)
+	}
+	return data, nil
+}
+
+func decodeBytes(data []byte, encoding string) ([]rune, error) {
+	switch normalizeEncoding(encoding) {
+	case "utf-8", "utf8":
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("invalid utf-8 data")
+		}
+		return []rune(string(data)), nil
+	case "utf-16le", "utf-16be":
+		if len(data)%2 != 0 {
+			return nil, fmt.Errorf("invalid %s data: odd length", encoding)
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if normalizeEncoding(encoding) == "utf-16le" {
+				units[i] = binary.LittleEndian.Uint16(data[2*i:])
+			} else {
+				units[i] = binary.BigEndian.Uint16(data[2*i:])
+			}
+		}
+		return utf16.Decode(units), nil
+	case "latin-1", "latin1", "iso-8859-1":
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return runes, nil
+	case "ascii":
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			if c > 0x7f {
+				return nil, fmt.Errorf("byte 0x%02x at %d is not ascii", c, i)
+			}
+			runes[i] = rune(c)
+		}
+		return runes, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", encoding)
+	}
+}
@@ -14,6 +14,47 @@ func NewEnvironment(mp *map[string]*Object) *Environment {
 type Environment struct {
 	store *map[string]*Object
 	outer *Environment
+	// file is the path of the script or module the environment runs
+	file string
+	// decimal is the decimal context of the script or module the environment
+	// runs
+	decimal *DecimalContext
+	// importing is the chain of modules being imported which led to the
+	// module the environment runs, ending with it
+	importing []string
+}
+
+// File returns the path of the script or module which env belongs to, it is
+// empty in the REPL.
+func (e *Environment) File() string {
+	for env := e; env != nil; env = env.outer {
+		if env.file != "" {
+			return env.file
+		}
+	}
+	return ""
+}
+
+// Importing returns the chain of modules being imported which led to the
+// module env belongs to, it is empty outside of modules.
+func (e *Environment) Importing() []string {
+	for env := e; env != nil; env = env.outer {
+		if env.file != "" {
+			return env.importing
+		}
+	}
+	return nil
+}
+
+// DecimalContext returns the decimal context of the script or module which env
+// belongs to, outside of them it is a new default one.
+func (e *Environment) DecimalContext() *DecimalContext {
+	for env := e; env != nil; env = env.outer {
+		if env.decimal != nil {
+			return env.decimal
+		}
+	}
+	return newDecimalContext()
 }
 
 func (e *Environment) Inspect(num int, env *Environment) string { return "(ENV)" }
@@ -2,13 +2,14 @@ package evaluator
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/mark07x/TLang/ast"
 	"github.com/mark07x/TLang/lexer"
 	"github.com/mark07x/TLang/parser"
 	"io"
-	"io/ioutil"
 	"math"
+	"math/big"
 	"os"
 	"reflect"
 	"strconv"
@@ -60,6 +61,9 @@ func init() {
 			return l
 		}}),
 		"cdlOpen": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if err := DefaultSandbox.Check("cdlOpen", CapFFI); err != nil {
+				return err
+			}
 			if len(args) != 1 {
 				return newError("native function cdlOpen: len(args) should be 1")
 			}
@@ -72,10 +76,13 @@ func init() {
 			return newError("native function cdlOpen: arg should be String")
 		}}),
 		"cdlSym": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if err := DefaultSandbox.Check("cdlSym", CapFFI); err != nil {
+				return err
+			}
 			if len(args) != 2 {
 				return newError("native function cdlSym: len(args) should be 2")
 			}
-			if i, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+			if i, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && i.Big == nil {
 				if str, ok := UnwrapReferenceValue(args[1]).(*String); ok {
 					cstr := C.CString(string(str.Value))
 					defer C.free(unsafe.Pointer(cstr))
@@ -92,11 +99,14 @@ func init() {
 			return newError("native function cdlSym: args[0] should be Int")
 		}}),
 		"cdlCall": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if err := DefaultSandbox.Check("cdlCall", CapFFI); err != nil {
+				return err
+			}
 			if len(args) != 4 {
 				return newError("native function cdlCall: len(args) should be 3")
 			}
 
-			if i, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+			if i, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && i.Big == nil {
 				if arrType, ok := UnwrapReferenceValue(args[1]).(*Array); ok {
 					if arrValue, ok := UnwrapReferenceValue(args[2]).(*Array); ok {
 						if retType, ok := UnwrapReferenceValue(args[3]).(*String); ok {
@@ -110,6 +120,21 @@ func init() {
 			}
 			return newError("native function cdlSym: args[0] should be Int")
 		}}),
+		"cdlBytes": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if err := DefaultSandbox.Check("cdlBytes", CapFFI); err != nil {
+				return err
+			}
+			if len(args) != 2 {
+				return newError("native function cdlBytes: len(args) should be 2")
+			}
+			if ptr, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && ptr.Big == nil {
+				if length, ok := UnwrapReferenceValue(args[1]).(*Integer); ok && length.Big == nil && length.Value >= 0 {
+					return &Bytes{Value: C.GoBytes(cPointer(ptr.Value), C.int(length.Value))}
+				}
+				return newError("native function cdlBytes: args[1] should be non-negative Integer")
+			}
+			return newError("native function cdlBytes: args[0] should be Int")
+		}}),
 		"super": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) != 2 {
 				return newError("native function super: len(args) should be 2")
@@ -188,17 +213,79 @@ func init() {
 			if len(args) != 0 {
 				return newError("native function inputLine: len(args) should be 0")
 			}
-			data, _, _ := bufio.NewReader(os.Stdin).ReadLine()
+			var data []byte
+			blocking(func() { data, _, _ = bufio.NewReader(os.Stdin).ReadLine() })
 
 			return &String{Value: []rune(string(data))}
 		}}),
 		"string": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 2 {
+				data, ok := UnwrapReferenceValue(args[0]).(*Bytes)
+				if !ok {
+					return newError("native function string: args[0] should be Bytes")
+				}
+				encoding, ok := UnwrapReferenceValue(args[1]).(*String)
+				if !ok {
+					return newError("native function string: args[1] should be String")
+				}
+				runes, err := decodeBytes(data.Value, string(encoding.Value))
+				if err != nil {
+					return newError("native function string: %s", err.Error())
+				}
+				return &String{Value: runes}
+			}
 			if len(args) != 1 {
-				return newError("native function string: len(args) should be 1")
+				return newError("native function string: len(args) should be 1 or 2")
 			}
 			un := UnwrapReferenceValue(args[0])
 			return toString(un, env)
 		}}),
+		"bytes": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 2 {
+				str, ok := UnwrapReferenceValue(args[0]).(*String)
+				if !ok {
+					return newError("native function bytes: args[0] should be String")
+				}
+				encoding, ok := UnwrapReferenceValue(args[1]).(*String)
+				if !ok {
+					return newError("native function bytes: args[1] should be String")
+				}
+				data, err := encodeString(str.Value, string(encoding.Value))
+				if err != nil {
+					return newError("native function bytes: %s", err.Error())
+				}
+				return &Bytes{Value: data}
+			}
+			if len(args) != 1 {
+				return newError("native function bytes: len(args) should be 1 or 2")
+			}
+			switch arg := UnwrapReferenceValue(args[0]).(type) {
+			case *Bytes:
+				return arg
+			case *Integer:
+				if arg.Value < 0 || arg.Big != nil {
+					return newError("native function bytes: length should be non-negative")
+				}
+				if arg.Value > maxBytesLen {
+					return newError("native function bytes: length should be at most %d", maxBytesLen)
+				}
+				return &Bytes{Value: make([]byte, arg.Value)}
+			case *Array:
+				data := make([]byte, len(arg.Elements))
+				for i, e := range arg.Elements {
+					b, ok := UnwrapReferenceValue(e).(*Integer)
+					if !ok || b.Big != nil || b.Value < 0 || b.Value > 255 {
+						return newError("native function bytes: element %d should be Integer in 0..255", i)
+					}
+					data[i] = byte(b.Value)
+				}
+				return &Bytes{Value: data}
+			case *String:
+				return newError("native function bytes: encoding of String should be given")
+			default:
+				return newError("native function bytes: arg should be Array, Integer or String")
+			}
+		}}),
 		"inspect": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) != 1 {
 				return newError("native function inspect: len(args) should be 1")
@@ -212,7 +299,7 @@ func init() {
 			}
 
 			if len(args) == 1 {
-				if val, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+				if val, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && val.Big == nil {
 					os.Exit(int(val.Value))
 				}
 				return newError("native function exit: arg should be Integer")
@@ -248,6 +335,9 @@ func init() {
 			case *String:
 				val, err := strconv.ParseInt(string(arg.Value), 10, 64)
 				if err != nil {
+					if b, ok := new(big.Int).SetString(string(arg.Value), 10); ok {
+						return NewBigInteger(b)
+					}
 					return newError("could not parse %s as integer", string(arg.Value))
 				}
 				return &Integer{Value: val}
@@ -264,7 +354,13 @@ func init() {
 					return &Integer{Value: 0}
 				}
 			case *Float:
+				if math.Abs(arg.Value) >= math.MaxInt64 && !math.IsInf(arg.Value, 0) {
+					b, _ := big.NewFloat(arg.Value).Int(nil)
+					return NewBigInteger(b)
+				}
 				return &Integer{Value: int64(arg.Value)}
+			case *Decimal:
+				return NewBigInteger(new(big.Int).Quo(arg.Value, pow10(arg.Scale)))
 			case *Integer:
 				return arg
 			case *Void:
@@ -298,7 +394,9 @@ func init() {
 					return &Float{Value: 0.}
 				}
 			case *Integer:
-				return &Float{Value: float64(arg.Value)}
+				return &Float{Value: arg.Float()}
+			case *Decimal:
+				return &Float{Value: arg.Float()}
 			case *Float:
 				return arg
 			case *Void:
@@ -308,6 +406,58 @@ func init() {
 			}
 		}}),
 
+		"decimal": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("native function decimal: len(args) should be 1, 2 or 3")
+			}
+			d, err := toDecimal(UnwrapReferenceValue(args[0]))
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return d
+			}
+			scale, err := scaleArg("decimal", args, 1)
+			if err != nil {
+				return err
+			}
+			mode := env.DecimalContext().Rounding
+			if len(args) == 3 {
+				str, ok := UnwrapReferenceValue(args[2]).(*String)
+				if !ok || !roundingModes[string(str.Value)] {
+					return newError("native function decimal: args[2] should be rounding mode")
+				}
+				mode = string(str.Value)
+			}
+			return roundDecimal(d.Rat(), scale, mode)
+		}}),
+
+		"decimalContext": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) > 2 {
+				return newError("native function decimalContext: len(args) should be 0, 1 or 2")
+			}
+			ctx := env.DecimalContext()
+			if len(args) == 0 {
+				return &Array{Elements: []Object{
+					&Integer{Value: int64(ctx.Scale)},
+					&String{Value: []rune(ctx.Rounding)},
+				}, Xvalue: true}
+			}
+			scale, err := scaleArg("decimalContext", args, 0)
+			if err != nil {
+				return err
+			}
+			if len(args) == 2 {
+				str, ok := UnwrapReferenceValue(args[1]).(*String)
+				if !ok || !roundingModes[string(str.Value)] {
+					return newError("native function decimalContext: args[1] should be rounding mode")
+				}
+				ctx.Rounding = string(str.Value)
+			}
+			ctx.Scale = scale
+			return VoidObj
+		}}),
+
 		"boolean": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) != 1 {
 				return newError("native function boolean: len(args) should be 1")
@@ -416,7 +566,7 @@ func init() {
 
 		"array": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) == 1 {
-				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && length.Big == nil {
 					var elem []Object
 					for i := int64(0); i < length.Value; i++ {
 						elem = append(elem, VoidObj)
@@ -429,7 +579,7 @@ func init() {
 				}
 				return newError("native function array: args[0] should be Integer")
 			} else if len(args) == 2 {
-				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && length.Big == nil {
 					var elem []Object
 					for i := int64(0); i < length.Value; i++ {
 						elem = append(elem, UnwrapReferenceValue(args[1]))
@@ -442,7 +592,7 @@ func init() {
 				}
 				return newError("native function array: args[0] should be Integer")
 			} else if len(args) == 3 {
-				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok {
+				if length, ok := UnwrapReferenceValue(args[0]).(*Integer); ok && length.Big == nil {
 					if function, ok := UnwrapReferenceValue(args[2]).(Functor); ok {
 						var elem []Object
 						e := UnwrapReferenceValue(args[1])
@@ -466,6 +616,43 @@ func init() {
 			return newError("native function array: len(args) should be 1, 2 or 3")
 		}}),
 
+		"tuple": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			elements := []Object{}
+			for _, arg := range args {
+				e := UnwrapReferenceValue(arg)
+				if _, ok := e.(HashAble); !ok {
+					return newError("native function tuple: unusable as tuple element: %s", e.Type())
+				}
+				elements = append(elements, e)
+			}
+			return &Tuple{Elements: elements}
+		}}),
+
+		"set": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			set := NewSet()
+			for _, arg := range args {
+				e := UnwrapReferenceValue(arg)
+				key, ok := e.(HashAble)
+				if !ok {
+					return newError("native function set: unusable as set element: %s", e.Type())
+				}
+				set.Add(key)
+			}
+			return set
+		}}),
+
+		"union": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			return applySetOperation("union", args)
+		}}),
+
+		"intersect": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			return applySetOperation("intersect", args)
+		}}),
+
+		"difference": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			return applySetOperation("difference", args)
+		}}),
+
 		"value": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) != 1 {
 				return newError("native function value: len(args) should be 1")
@@ -491,38 +678,58 @@ func init() {
 			}
 		}}),
 
+		"try": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 0 {
+				return newError("native function try: len(args) should be at least 1")
+			}
+			result := UnwrapReferenceValue(applyCall(UnwrapReferenceValue(args[0]), args[1:], env))
+			if err, ok := result.(*Err); ok {
+				return &Array{Elements: []Object{VoidObj, &String{Value: []rune(err.Message)}}, Xvalue: true}
+			}
+			return &Array{Elements: []Object{result, VoidObj}, Xvalue: true}
+		}}),
+
 		"import": makeObjectPointer(&Native{Fn: func(env *Environment, args []Object) Object {
 			if len(args) != 1 {
 				return newError("native function import: len(args) should be 1")
 			}
 			if str, ok := UnwrapReferenceValue(args[0]).(*String); ok {
-				data, err := ioutil.ReadFile(string(str.Value))
-				if err != nil {
-					return newError("unable to read file %s: %s", string(str.Value), err.Error())
-				}
-				l := lexer.New(string(data))
-				p := parser.New(l)
-
-				program := p.ParseProgram()
-				if len(p.Errors()) != 0 {
-					PrintParserErrors(os.Stdout, p.Errors())
-					return newError("error inner import")
-				}
-
-				importEnv := SharedEnv.NewEnclosedEnvironment()
-				result := Eval(program, importEnv)
-				if isError(result) {
-					return result
-				}
-				if export, ok := importEnv.Get("export"); ok {
-					return *export
-				}
-				return VoidObj
-				//return newError("native function import: export obj not found")
+				return importModule(string(str.Value), env)
 			}
 			return newError("native function import: arg should be String")
 		}}),
 	})
+	stringNatives = newStringNatives()
+	arrayNatives = newArrayNatives()
+	hashNatives = newHashNatives()
+	// find and reverse are shared by String and Array, remove by Hash and Array
+	for _, natives := range []map[string]*Native{stringNatives, hashNatives} {
+		for name, fn := range natives {
+			if arrayFn, ok := arrayNatives[name]; ok {
+				fn = overloadNative(arrayFn, fn)
+			}
+			SharedEnv.SetCurrent(name, fn)
+		}
+	}
+	for name, fn := range arrayNatives {
+		SharedEnv.SetCurrent(name, fn)
+	}
+	resetPrototypes()
+	SharedEnv.SetCurrent("math", newMathModule())
+	SharedEnv.SetCurrent("random", newRandomModule())
+	SharedEnv.SetCurrent("time", newTimeModule())
+	SharedEnv.SetCurrent("fs", newFsModule())
+	SharedEnv.SetCurrent("sandbox", newSandboxModule())
+	SharedEnv.SetCurrent("os", newOsModule())
+	SharedEnv.SetCurrent("process", newProcessModule())
+	SharedEnv.SetCurrent("json", newJsonModule())
+	SharedEnv.SetCurrent("regex", newRegexModule())
+	SharedEnv.SetCurrent("csv", newCsvModule())
+	SharedEnv.SetCurrent("base64", newBase64Module())
+	SharedEnv.SetCurrent("hex", newHexModule())
+	SharedEnv.SetCurrent("digest", newDigestModule())
+	SharedEnv.SetCurrent("http", newHttpModule())
+	SharedEnv.SetCurrent("tcp", newTcpModule())
 	SharedEnv.SetCurrent("#", code(`
 				{
 					"switch": func(v) {
@@ -676,68 +883,10 @@ func init() {
 							}
 						},
 					},
-					"max": _ {
-						if (len(args) == 0) {
-							ret void
-						};
-						if (len(args) == 1 and type(args[0]) == "Array") {
-							if (len(args[0]) == 0) {
-								ret void
-							};
-							let maximum = args[0][0]
-							loop x in (args[0]) {
-								maximum = if (x > maximum) { x; } else { maximum; }
-							};
-							ret maximum
-						} else {
-							ret #max(args)
-						};
-					},
-				
-					"min": _ {
-						if (len(args) == 0) {
-							ret void
-						};
-						if (len(args) == 1 and type(args[0]) == "Array") {
-							if (len(args[0]) == 0) {
-								ret void
-							}
-							let minimum = args[0][0];
-							loop x in (args[0]) {
-								minimum = if (x < minimum) { x; } else { minimum; }
-							}
-							ret minimum
-						} else {
-							ret #min(args)
-						}
-					},
-				
-					"abs": _ {
-						if (len args != 1) {
-							ret void
-						}
-						ret if (args[0] < 0) { -args[0]; } else { args[0]; }
-					},
-				
-					"sqrt": _ {
-						if (len args != 1) {
-							ret void
-						};
-						let L = 0
-						let R = #max(1, args[0])
-						ret integer((loop (R - L >= 1e-12) {
-							let M = (L + R) / 2
-							let K = M * M
-							if (#.abs(K - args[0]) <= 1e-12) {
-								out M
-							};
-							if (K > args[0]) {
-								R
-							} else if (K < args[0]) {
-								L
-							} = M
-						} * 1e11 + 5) / 10) / 1e10
-					},
+					"max": math.max,
+					"min": math.min,
+					"abs": math.abs,
+					"sqrt": math.sqrt,
 				
 					"about": _ {
 						printLine()
@@ -778,6 +927,9 @@ func Eval(node ast.Node, env *Environment) Object {
 		return UnwrapReferenceValue(evalProgram(node, env))
 
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return NewBigInteger(new(big.Int).Set(node.Big))
+		}
 		return &Integer{Value: node.Value}
 	case *ast.FloatLiteral:
 		return &Float{Value: node.Value}
@@ -855,6 +1007,8 @@ func Eval(node ast.Node, env *Environment) Object {
 			return indexes[0]
 		}
 		return applyIndex(ident, indexes, Default, env)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.DotExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -898,6 +1052,10 @@ func Eval(node ast.Node, env *Environment) Object {
 		if _, ok := env.SetCurrent(node.Name.Value, val.Copy()); !ok {
 			return newError("identifier %s already set", node.Name.Value)
 		}
+	case *ast.ImportStatement:
+		if err := evalImportStatement(node, env); err != nil {
+			return err
+		}
 	case *ast.DelStatement:
 		if ident, ok := node.DelIdent.(*ast.Identifier); ok {
 			if _, ok := env.Get(ident.Value); ok {
@@ -970,19 +1128,42 @@ func template(hash *Hash) (*Hash, bool) {
 }
 
 func applyIndex(obj Object, indexes []Object, flag classFlag, env *Environment) Object {
+	receiver := obj
 	constObj := true
 	if refer, ok := obj.(*Reference); ok {
 		constObj = refer.Const
 		obj = UnwrapReferenceValue(obj)
 	}
 
+	if len(indexes) == 1 && obj.Type() != HASH {
+		if name, ok := indexes[0].(*String); ok {
+			if method, ok := prototypeMethod(receiver, name); ok {
+				return method
+			}
+		}
+	}
+
 	if arr, ok := obj.(*Array); ok {
 		if len(indexes) != 1 {
 			return newError("array: len(indexes) should be 1")
 		}
+		if slice, ok := indexes[0].(*Slice); ok {
+			start, stop, step, err := sliceIndices(slice, int64(len(arr.Elements)))
+			if err != nil {
+				return err
+			}
+			elements := []Object{}
+			for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+				elements = append(elements, UnwrapReferenceValue(arr.Elements[i]).Copy())
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}
 		if indexes[0].Type() != INTEGER {
 			return newError("array: index should be Integer")
 		}
+		if indexes[0].(*Integer).Big != nil {
+			return newError("array: index is not a machine integer")
+		}
 		index := indexes[0].(*Integer).Value
 		length := int64(len(arr.Elements))
 		if index >= length || index < 0 {
@@ -994,14 +1175,105 @@ func applyIndex(obj Object, indexes []Object, flag classFlag, env *Environment)
 		}
 		return &Reference{Value: refObj, Const: constObj}
 	}
+	if tuple, ok := obj.(*Tuple); ok {
+		if len(indexes) != 1 {
+			return newError("tuple: len(indexes) should be 1")
+		}
+		if slice, ok := indexes[0].(*Slice); ok {
+			start, stop, step, err := sliceIndices(slice, int64(len(tuple.Elements)))
+			if err != nil {
+				return err
+			}
+			elements := []Object{}
+			for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+				elements = append(elements, tuple.Elements[i])
+			}
+			return &Tuple{Elements: elements}
+		}
+		if indexes[0].Type() != INTEGER {
+			return newError("tuple: index should be Integer")
+		}
+		if indexes[0].(*Integer).Big != nil {
+			return newError("tuple: index is not a machine integer")
+		}
+		index := indexes[0].(*Integer).Value
+		length := int64(len(tuple.Elements))
+		if index >= length || index < 0 {
+			return newError("tuple: out of range")
+		}
+		return &Reference{Value: &tuple.Elements[index], Const: true}
+	}
+	if set, ok := obj.(*Set); ok {
+		if len(indexes) != 1 {
+			return newError("set: len(indexes) should be 1")
+		}
+		if indexes[0].Type() != INTEGER {
+			return newError("set: index should be Integer")
+		}
+		if indexes[0].(*Integer).Big != nil {
+			return newError("set: index is not a machine integer")
+		}
+		index := indexes[0].(*Integer).Value
+		length := int64(len(set.Elements))
+		if index >= length || index < 0 {
+			return newError("set: out of range")
+		}
+		return &Reference{Value: &set.Elements[index], Const: true}
+	}
+	if data, ok := obj.(*Bytes); ok {
+		if len(indexes) != 1 {
+			return newError("bytes: len(indexes) should be 1")
+		}
+		if slice, ok := indexes[0].(*Slice); ok {
+			start, stop, step, err := sliceIndices(slice, int64(len(data.Value)))
+			if err != nil {
+				return err
+			}
+			result := []byte{}
+			for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+				result = append(result, data.Value[i])
+			}
+			return &Bytes{Value: result}
+		}
+		if indexes[0].Type() != INTEGER {
+			return newError("bytes: index should be Integer")
+		}
+		if indexes[0].(*Integer).Big != nil {
+			return newError("bytes: index is not a machine integer")
+		}
+		index := indexes[0].(*Integer).Value
+		length := int64(len(data.Value))
+		if index >= length || index < 0 {
+			return newError("bytes: out of range")
+		}
+		var b Object = &Integer{Value: int64(data.Value[index])}
+		return &Reference{Value: &b, Const: true}
+	}
 	if str, ok := obj.(*String); ok {
 		//runeStr := []rune(str.Value)
 		if len(indexes) != 1 {
 			return newError("string: len(indexes) should be 1")
 		}
+		if slice, ok := indexes[0].(*Slice); ok {
+			start, stop, step, err := sliceIndices(slice, int64(len(str.Value)))
+			if err != nil {
+				return err
+			}
+			var runes []rune
+			for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+				runes = append(runes, str.Value[i])
+			}
+			return &String{Value: runes}
+		}
+		if name, ok := indexes[0].(*String); ok {
+			return newError("string: method %s not found", string(name.Value))
+		}
 		if indexes[0].Type() != INTEGER {
 			return newError("string: index should be Integer")
 		}
+		if indexes[0].(*Integer).Big != nil {
+			return newError("string: index is not a machine integer")
+		}
 		index := indexes[0].(*Integer).Value
 		length := int64(len(str.Value))
 		if index >= length || index < 0 {
@@ -1058,16 +1330,94 @@ func applyIndex(obj Object, indexes []Object, flag classFlag, env *Environment)
 					return applyCall(ref, []Object{&Array{Elements: indexes}}, env)
 				}
 			}
-			return &Reference{Value: nil, Const: constObj, Origin: hashOld, Index: key}
+			method, _ := prototypeMethod(receiver, key)
+			return &Reference{Value: nil, Const: constObj, Origin: hashOld, Index: key, Method: method}
 		}
 	}
 	return newError("not Array, String or Hash: %s", obj.Type())
 }
 
+func sliceIndices(slice *Slice, length int64) (int64, int64, int64, *Err) {
+	step := int64(1)
+	switch v := slice.Step.(type) {
+	case *Integer:
+		if v.Big != nil {
+			return 0, 0, 0, newError("slice: step is not a machine integer")
+		}
+		if v.Value == 0 {
+			return 0, 0, 0, newError("slice: step should not be 0")
+		}
+		step = v.Value
+	case *Void:
+	default:
+		return 0, 0, 0, newError("slice: step should be Integer")
+	}
+
+	bound := func(obj Object, def int64) (int64, *Err) {
+		switch v := obj.(type) {
+		case *Integer:
+			index := v.Value
+			if v.Big != nil {
+				// a bound outside int64 is outside the sequence too
+				index = -length - 1
+				if v.Big.Sign() > 0 {
+					index = length
+				}
+			}
+			if index < 0 {
+				index += length
+				if index < 0 {
+					if step < 0 {
+						return -1, nil
+					}
+					return 0, nil
+				}
+			} else if index >= length {
+				if step < 0 {
+					return length - 1, nil
+				}
+				return length, nil
+			}
+			return index, nil
+		case *Void:
+			return def, nil
+		default:
+			return 0, newError("slice: index should be Integer")
+		}
+	}
+
+	var start, stop int64
+	var err *Err
+	if step > 0 {
+		start, err = bound(slice.Start, 0)
+		if err == nil {
+			stop, err = bound(slice.End, length)
+		}
+	} else {
+		start, err = bound(slice.Start, length-1)
+		if err == nil {
+			stop, err = bound(slice.End, -1)
+		}
+	}
+	return start, stop, step, err
+}
+
+// cPointer gives the C address addr as a pointer, the memory belongs to C, so
+// the garbage collector never moves it
+func cPointer(addr int64) unsafe.Pointer {
+	p := uintptr(addr)
+	return *(*unsafe.Pointer)(unsafe.Pointer(&p))
+}
+
 func applyCdlCall(id int64, argsType []Object, argsValue []Object, retType TypeC, env *Environment) Object {
 	if len(argsType) != len(argsValue) {
 		return newError("len(argsType) != len(argsValue)")
 	}
+	for i, arg := range argsValue {
+		if integer, ok := UnwrapReferenceValue(arg).(*Integer); ok && integer.Big != nil {
+			return newError("cdlCall: args[%d] is not a machine integer", i)
+		}
+	}
 	l := len(argsType)
 
 	var cif = (*C.ffi_cif)(C.malloc(C.sizeof_ffi_cif))
@@ -1110,7 +1460,7 @@ func applyCdlCall(id int64, argsType []Object, argsValue []Object, retType TypeC
 				cMem = C.malloc(C.sizeof_double)
 				*(*float64)(cMem) = UnwrapReferenceValue(argsValue[i]).(*Float).Value
 				argsValueFFI[i] = cMem
-			case "pointer", "string":
+			case "pointer", "string", "bytes":
 				argsTypeFFI[i] = &C.ffi_type_pointer
 				cMem = C.malloc(C.sizeof_size_t)
 				switch v := UnwrapReferenceValue(argsValue[i]).(type) {
@@ -1120,6 +1470,13 @@ func applyCdlCall(id int64, argsType []Object, argsValue []Object, retType TypeC
 					p := unsafe.Pointer(C.CString(string(v.Value)))
 					*(*unsafe.Pointer)(cMem) = p
 					defer C.free(p)
+				case *Bytes:
+					// Bytes are values, the C function gets a copy, and what it
+					// writes there is lost, pass a "pointer" to C memory and read
+					// it with cdlBytes(ptr, len) to get output
+					p := C.CBytes(v.Value)
+					*(*unsafe.Pointer)(cMem) = p
+					defer C.free(p)
 				default:
 					*(*unsafe.Pointer)(cMem) = unsafe.Pointer(uintptr(0))
 				}
@@ -1149,7 +1506,7 @@ func applyCdlCall(id int64, argsType []Object, argsValue []Object, retType TypeC
 	case "double":
 		rc = C.malloc(C.sizeof_double)
 		rt = &C.ffi_type_double
-	case "pointer", "string":
+	case "pointer", "string", "bytes":
 		rc = C.malloc(C.sizeof_size_t)
 		rt = &C.ffi_type_pointer
 	default:
@@ -1170,6 +1527,11 @@ func applyCdlCall(id int64, argsType []Object, argsValue []Object, retType TypeC
 			return code("#.CType("+strconv.FormatInt(int64(uintptr(*(*unsafe.Pointer)(rc))), 10)+", \"pointer\");", env)
 		case "string":
 			return &String{Value: []rune(C.GoString(*(**C.char)(rc)))}
+		case "bytes":
+			// the length is not known, so the result ends at the first NUL,
+			// return a "pointer" and use cdlBytes(ptr, len) for binary data
+			p := *(**C.char)(rc)
+			return &Bytes{Value: C.GoBytes(unsafe.Pointer(p), C.int(C.strlen(p)))}
 		default:
 			return VoidObj
 		}
@@ -1308,9 +1670,10 @@ func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *Environment,
 ) Object {
-	pairs := make(map[HashKey]HashPair)
+	hash := &Hash{Pairs: make(map[HashKey]HashPair)}
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
 		key := UnwrapReferenceValue(Eval(keyNode, env))
 		if isError(key) {
 			return key
@@ -1326,11 +1689,10 @@ func evalHashLiteral(
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = HashPair{Key: key, Value: &value}
+		hash.Set(hashKey.HashKey(), HashPair{Key: key, Value: &value})
 	}
 
-	return &Hash{Pairs: pairs}
+	return hash
 }
 
 func evalProgram(program *ast.Program, env *Environment) Object {
@@ -1398,7 +1760,94 @@ func evalPrefixExpression(operator string, right Object) Object {
 	}
 }
 
+func evalSliceExpression(node *ast.SliceExpression, env *Environment) Object {
+	var parts []Object
+	for _, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+		if exp == nil {
+			parts = append(parts, VoidObj)
+			continue
+		}
+		val := UnwrapReferenceValue(Eval(exp, env))
+		if isError(val) {
+			return val
+		}
+		parts = append(parts, val)
+	}
+	return &Slice{Start: parts[0], End: parts[1], Step: parts[2]}
+}
+
+func evalSliceAssignExpression(node *ast.AssignExpression, index *ast.IndexExpression, env *Environment) Object {
+	val := UnwrapReferenceValue(Eval(node.Value, env))
+	if isError(val) {
+		return val
+	}
+
+	left := Eval(index.Left, env)
+	if isError(left) {
+		return left
+	}
+	slice := Eval(index.Indexes[0], env)
+	if isError(slice) {
+		return slice
+	}
+	refer, ok := left.(*Reference)
+	if !ok {
+		return newError("left value not Reference: %s", left.Inspect(16, env))
+	}
+	arr, ok := UnwrapReferenceValue(refer).(*Array)
+	if !ok {
+		return newError("slice assign: left value should be Array, got %s", UnwrapReferenceValue(refer).Type())
+	}
+
+	if node.Operator != "=" {
+		val = evalInfixExpression(node.Operator[:1], applyIndex(arr, []Object{slice}, Default, env), val, env)
+		if isError(val) {
+			return val
+		}
+	}
+	if refer.Const {
+		return newError("assign to const reference")
+	}
+	valArr, ok := val.(*Array)
+	if !ok {
+		return newError("slice assign: value should be Array, got %s", val.Type())
+	}
+	elements := valArr.Copy().(*Array).Elements
+
+	start, stop, step, err := sliceIndices(slice.(*Slice), int64(len(arr.Elements)))
+	if err != nil {
+		return err
+	}
+	if step == 1 {
+		if stop < start {
+			stop = start
+		}
+		newElements := append([]Object{}, arr.Elements[:start]...)
+		newElements = append(newElements, elements...)
+		arr.Elements = append(newElements, arr.Elements[stop:]...)
+		return valArr
+	}
+
+	var positions []int64
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		positions = append(positions, i)
+	}
+	if len(positions) != len(elements) {
+		return newError("slice assign: size of value %d should be %d", len(elements), len(positions))
+	}
+	for i, pos := range positions {
+		arr.Elements[pos] = elements[i]
+	}
+	return valArr
+}
+
 func evalAssignExpression(node *ast.AssignExpression, env *Environment) Object {
+	if index, ok := node.Left.(*ast.IndexExpression); ok && len(index.Indexes) == 1 {
+		if _, ok := index.Indexes[0].(*ast.SliceExpression); ok {
+			return evalSliceAssignExpression(node, index, env)
+		}
+	}
+
 	val := UnwrapReferenceValue(Eval(node.Value, env))
 	if isError(val) {
 		return val
@@ -1450,6 +1899,18 @@ func evalInfixExpression(
 	env *Environment,
 ) Object {
 	switch {
+	case operator == "in":
+		return evalInInfixExpression(left, right, env)
+
+	case left.Type() == DECIMAL || right.Type() == DECIMAL:
+		if _, ok := left.(Number); ok {
+			if _, ok := right.(Number); ok {
+				return evalDecimalInfixExpression(operator, left, right, env)
+			}
+		}
+		return newError("type mismatch: %s %s %s",
+			left.Type(), operator, right.Type())
+
 	case left.Type() == INTEGER || left.Type() == FLOAT:
 		if right.Type() == INTEGER || right.Type() == FLOAT {
 			return evalNumberInfixExpression(operator, left, right)
@@ -1471,10 +1932,34 @@ func evalInfixExpression(
 		return newError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
 
+	case left.Type() == ARRAY || left.Type() == TUPLE:
+		if right.Type() == left.Type() {
+			return evalSequenceInfixExpression(operator, left, right, env)
+		}
+		return newError("type mismatch: %s %s %s",
+			left.Type(), operator, right.Type())
+
+	case left.Type() == BYTES:
+		if right.Type() == BYTES {
+			return evalBytesInfixExpression(operator, left.(*Bytes), right.(*Bytes))
+		}
+		return newError("type mismatch: %s %s %s",
+			left.Type(), operator, right.Type())
+
+	case left.Type() == SET:
+		if right.Type() == SET {
+			return evalSetInfixExpression(operator, left.(*Set), right.(*Set))
+		}
+		return newError("type mismatch: %s %s %s",
+			left.Type(), operator, right.Type())
+
 	case left.Type() == HASH:
 		return evalHashInfixExpression(operator, left.(*Hash), right, env)
 
 	default:
+		if method, ok := prototypeMethod(left, &String{Value: []rune("@" + operator)}); ok {
+			return applyCall(method, []Object{right}, env)
+		}
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
@@ -1486,11 +1971,249 @@ func evalHashInfixExpression(
 	right Object,
 	env *Environment,
 ) Object {
-	return applyCall(
-		applyIndex(left, []Object{&String{Value: []rune("@" + operator)}}, Default, env),
-		[]Object{right, left},
-		env,
-	)
+	ref := applyIndex(left, []Object{&String{Value: []rune("@" + operator)}}, Default, env).(*Reference)
+	if ref.Value == nil {
+		switch operator {
+		case "==":
+			return nativeBoolToBooleanObject(objectEqual(left, right, env, map[[2]Object]bool{}))
+		case "!=":
+			return nativeBoolToBooleanObject(!objectEqual(left, right, env, map[[2]Object]bool{}))
+		}
+	}
+	return applyCall(ref, []Object{right, left}, env)
+}
+
+func evalInInfixExpression(
+	left, right Object,
+	env *Environment,
+) Object {
+	switch right := right.(type) {
+	case *Set:
+		if key, ok := left.(HashAble); ok {
+			return nativeBoolToBooleanObject(right.Contains(key))
+		}
+		return FalseObj
+	case *Hash:
+		if key, ok := left.(HashAble); ok {
+			_, ok := right.Pairs[key.HashKey()]
+			return nativeBoolToBooleanObject(ok)
+		}
+		return FalseObj
+	case *Array, *Tuple:
+		for _, e := range sequenceElements(right) {
+			if objectEqual(left, e, env, map[[2]Object]bool{}) {
+				return TrueObj
+			}
+		}
+		return FalseObj
+	case *String:
+		if letter, ok := left.(Letter); ok {
+			return nativeBoolToBooleanObject(strings.Contains(string(right.Value), letter.LetterObj()))
+		}
+		return newError("type mismatch: %s in %s", left.Type(), right.Type())
+	default:
+		return newError("unknown operator: %s in %s", left.Type(), right.Type())
+	}
+}
+
+func evalSetInfixExpression(
+	operator string,
+	left, right *Set,
+) Object {
+	switch operator {
+	case "+":
+		return setOperation("union", left, right)
+	case "-":
+		return setOperation("difference", left, right)
+	case "==":
+		return nativeBoolToBooleanObject(isSubset(left, right) && len(left.Elements) == len(right.Elements))
+	case "!=":
+		return nativeBoolToBooleanObject(!isSubset(left, right) || len(left.Elements) != len(right.Elements))
+	case "<=":
+		return nativeBoolToBooleanObject(isSubset(left, right))
+	case ">=":
+		return nativeBoolToBooleanObject(isSubset(right, left))
+	case "<":
+		return nativeBoolToBooleanObject(isSubset(left, right) && len(left.Elements) < len(right.Elements))
+	case ">":
+		return nativeBoolToBooleanObject(isSubset(right, left) && len(left.Elements) > len(right.Elements))
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func isSubset(left, right *Set) bool {
+	for key := range left.Keys {
+		if _, ok := right.Keys[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func setOperation(operation string, left, right *Set) *Set {
+	set := NewSet()
+	switch operation {
+	case "union":
+		for _, e := range left.Elements {
+			set.Add(e.(HashAble))
+		}
+		for _, e := range right.Elements {
+			set.Add(e.(HashAble))
+		}
+	case "intersect":
+		for _, e := range left.Elements {
+			if right.Contains(e.(HashAble)) {
+				set.Add(e.(HashAble))
+			}
+		}
+	case "difference":
+		for _, e := range left.Elements {
+			if !right.Contains(e.(HashAble)) {
+				set.Add(e.(HashAble))
+			}
+		}
+	}
+	return set
+}
+
+func applySetOperation(operation string, args []Object) Object {
+	if len(args) == 0 {
+		return newError("native function %s: len(args) should be at least 1", operation)
+	}
+	var result *Set
+	for i, arg := range args {
+		set, ok := UnwrapReferenceValue(arg).(*Set)
+		if !ok {
+			return newError("native function %s: args[%d] should be Set", operation, i)
+		}
+		if result == nil {
+			result = set
+		} else {
+			result = setOperation(operation, result, set)
+		}
+	}
+	return result
+}
+
+func sequenceElements(obj Object) []Object {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements
+	case *Tuple:
+		return obj.Elements
+	default:
+		return nil
+	}
+}
+
+func evalSequenceInfixExpression(
+	operator string,
+	left, right Object,
+	env *Environment,
+) Object {
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(objectEqual(left, right, env, map[[2]Object]bool{}))
+	case "!=":
+		return nativeBoolToBooleanObject(!objectEqual(left, right, env, map[[2]Object]bool{}))
+	case "<", ">", "<=", ">=":
+		cmp, err := compareSequence(sequenceElements(left), sequenceElements(right), env)
+		if err != nil {
+			return err
+		}
+		switch operator {
+		case "<":
+			return nativeBoolToBooleanObject(cmp < 0)
+		case ">":
+			return nativeBoolToBooleanObject(cmp > 0)
+		case "<=":
+			return nativeBoolToBooleanObject(cmp <= 0)
+		default:
+			return nativeBoolToBooleanObject(cmp >= 0)
+		}
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func compareSequence(left, right []Object, env *Environment) (int, Object) {
+	for i := 0; i < len(left) && i < len(right); i++ {
+		l, r := UnwrapReferenceValue(left[i]), UnwrapReferenceValue(right[i])
+		if objectEqual(l, r, env, map[[2]Object]bool{}) {
+			continue
+		}
+		less := evalInfixExpression("<", l, r, env)
+		if isError(less) {
+			return 0, less
+		}
+		if isTruthy(less) {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	switch {
+	case len(left) < len(right):
+		return -1, nil
+	case len(left) > len(right):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func objectEqual(left, right Object, env *Environment, seen map[[2]Object]bool) bool {
+	left, right = UnwrapReferenceValue(left), UnwrapReferenceValue(right)
+	if left == right {
+		return true
+	}
+	if seen[[2]Object{left, right}] {
+		return true
+	}
+
+	switch l := left.(type) {
+	case *Array, *Tuple:
+		if left.Type() != right.Type() {
+			return false
+		}
+		le, re := sequenceElements(left), sequenceElements(right)
+		if len(le) != len(re) {
+			return false
+		}
+		seen[[2]Object{left, right}] = true
+		for i := range le {
+			if !objectEqual(le[i], re[i], env, seen) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		ref := applyIndex(l, []Object{&String{Value: []rune("@==")}}, Default, env).(*Reference)
+		if ref.Value != nil {
+			return isTruthy(UnwrapReferenceValue(applyCall(ref, []Object{right, left}, env)))
+		}
+		r, ok := right.(*Hash)
+		if !ok || len(l.Pairs) != len(r.Pairs) {
+			return false
+		}
+		seen[[2]Object{left, right}] = true
+		for key, pair := range l.Pairs {
+			rPair, ok := r.Pairs[key]
+			if !ok || !objectEqual(*pair.Value, *rPair.Value, env, seen) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		r, ok := right.(*Set)
+		return ok && isSubset(l, r) && len(l.Elements) == len(r.Elements)
+	case *Void:
+		return false
+	default:
+		return evalInfixExpression("==", left, right, env) == TrueObj
+	}
 }
 
 func evalBooleanInfixExpression(
@@ -1521,6 +2244,14 @@ func evalStringInfixExpression(
 		return nativeBoolToBooleanObject(left == right)
 	case "!=":
 		return nativeBoolToBooleanObject(left != right)
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
 	case "+":
 		return &String{Value: []rune(left + right)}
 	default:
@@ -1529,6 +2260,31 @@ func evalStringInfixExpression(
 	}
 }
 
+func evalBytesInfixExpression(
+	operator string,
+	left, right *Bytes,
+) Object {
+	switch operator {
+	case "+":
+		return &Bytes{Value: append(append([]byte{}, left.Value...), right.Value...)}
+	case "==":
+		return nativeBoolToBooleanObject(bytes.Equal(left.Value, right.Value))
+	case "!=":
+		return nativeBoolToBooleanObject(!bytes.Equal(left.Value, right.Value))
+	case "<":
+		return nativeBoolToBooleanObject(bytes.Compare(left.Value, right.Value) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(bytes.Compare(left.Value, right.Value) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(bytes.Compare(left.Value, right.Value) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(bytes.Compare(left.Value, right.Value) >= 0)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
 func evalNumberInfixExpression(
 	operator string,
 	left, right Object,
@@ -1548,20 +2304,42 @@ func evalIntegerInfixExpression(
 	operator string,
 	left, right Object,
 ) Object {
-	leftVal := left.(*Integer).Value
+	leftInt := left.(*Integer)
+	leftVal := leftInt.Value
 	switch right.Type() {
 	case INTEGER:
-		rightVal := right.(*Integer).Value
+		rightInt := right.(*Integer)
+		if leftInt.Big != nil || rightInt.Big != nil {
+			return evalBigIntegerInfixExpression(operator, leftInt, rightInt)
+		}
+		rightVal := rightInt.Value
 		switch operator {
 		case "+":
-			return &Integer{Value: leftVal + rightVal}
+			if result := leftVal + rightVal; (result > leftVal) == (rightVal > 0) {
+				return &Integer{Value: result}
+			}
+			return evalBigIntegerInfixExpression(operator, leftInt, rightInt)
 		case "-":
-			return &Integer{Value: leftVal - rightVal}
+			if result := leftVal - rightVal; (result < leftVal) == (rightVal > 0) {
+				return &Integer{Value: result}
+			}
+			return evalBigIntegerInfixExpression(operator, leftInt, rightInt)
 		case "*":
-			return &Integer{Value: leftVal * rightVal}
+			if leftVal == 0 || rightVal == 0 {
+				return &Integer{Value: 0}
+			}
+			result := leftVal * rightVal
+			if result/rightVal == leftVal && !(leftVal == -1 && rightVal == math.MinInt64) &&
+				!(rightVal == -1 && leftVal == math.MinInt64) {
+				return &Integer{Value: result}
+			}
+			return evalBigIntegerInfixExpression(operator, leftInt, rightInt)
 		case "/":
 			return &Float{Value: float64(leftVal) / float64(rightVal)}
 		case "%":
+			if rightVal == 0 {
+				return newError("division by zero: %s %% 0", left.Inspect(16, nil))
+			}
 			return &Integer{Value: leftVal % rightVal}
 
 		case "<":
@@ -1582,29 +2360,30 @@ func evalIntegerInfixExpression(
 				left.Type(), operator, right.Type())
 		}
 	case FLOAT:
+		leftVal := leftInt.Float()
 		rightVal := right.(*Float).Value
 		switch operator {
 		case "+":
-			return &Float{Value: float64(leftVal) + rightVal}
+			return &Float{Value: leftVal + rightVal}
 		case "-":
-			return &Float{Value: float64(leftVal) - rightVal}
+			return &Float{Value: leftVal - rightVal}
 		case "*":
-			return &Float{Value: float64(leftVal) * rightVal}
+			return &Float{Value: leftVal * rightVal}
 		case "/":
-			return &Float{Value: float64(leftVal) / rightVal}
+			return &Float{Value: leftVal / rightVal}
 
 		case "<":
-			return nativeBoolToBooleanObject(float64(leftVal) < rightVal)
+			return nativeBoolToBooleanObject(leftVal < rightVal)
 		case ">":
-			return nativeBoolToBooleanObject(float64(leftVal) > rightVal)
+			return nativeBoolToBooleanObject(leftVal > rightVal)
 		case "<=":
-			return nativeBoolToBooleanObject(float64(leftVal) <= rightVal)
+			return nativeBoolToBooleanObject(leftVal <= rightVal)
 		case ">=":
-			return nativeBoolToBooleanObject(float64(leftVal) >= rightVal)
+			return nativeBoolToBooleanObject(leftVal >= rightVal)
 		case "==":
-			return nativeBoolToBooleanObject(float64(leftVal) == rightVal)
+			return nativeBoolToBooleanObject(leftVal == rightVal)
 		case "!=":
-			return nativeBoolToBooleanObject(float64(leftVal) != rightVal)
+			return nativeBoolToBooleanObject(leftVal != rightVal)
 
 		default:
 			return newError("unknown operator: %s %s %s",
@@ -1616,6 +2395,49 @@ func evalIntegerInfixExpression(
 	}
 }
 
+func evalBigIntegerInfixExpression(
+	operator string,
+	left, right *Integer,
+) Object {
+	leftVal, rightVal := left.BigInt(), right.BigInt()
+	switch operator {
+	case "+":
+		return NewBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return NewBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return NewBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return &Float{Value: left.Float() / right.Float()}
+		}
+		quo, _ := new(big.Float).Quo(new(big.Float).SetInt(leftVal), new(big.Float).SetInt(rightVal)).Float64()
+		return &Float{Value: quo}
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero: %s %% 0", left.Inspect(16, nil))
+		}
+		return NewBigInteger(new(big.Int).Rem(leftVal, rightVal))
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
 func evalFloatInfixExpression(
 	operator string,
 	left, right Object,
@@ -1623,29 +2445,29 @@ func evalFloatInfixExpression(
 	leftVal := left.(*Float).Value
 	switch right.Type() {
 	case INTEGER:
-		rightVal := right.(*Integer).Value
+		rightVal := right.(*Integer).Float()
 		switch operator {
 		case "+":
-			return &Float{Value: leftVal + float64(rightVal)}
+			return &Float{Value: leftVal + rightVal}
 		case "-":
-			return &Float{Value: leftVal - float64(rightVal)}
+			return &Float{Value: leftVal - rightVal}
 		case "*":
-			return &Float{Value: leftVal * float64(rightVal)}
+			return &Float{Value: leftVal * rightVal}
 		case "/":
-			return &Float{Value: leftVal / float64(rightVal)}
+			return &Float{Value: leftVal / rightVal}
 
 		case "<":
-			return nativeBoolToBooleanObject(leftVal < float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal < rightVal)
 		case ">":
-			return nativeBoolToBooleanObject(leftVal > float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal > rightVal)
 		case "<=":
-			return nativeBoolToBooleanObject(leftVal <= float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal <= rightVal)
 		case ">=":
-			return nativeBoolToBooleanObject(leftVal >= float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal >= rightVal)
 		case "==":
-			return nativeBoolToBooleanObject(leftVal == float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal == rightVal)
 		case "!=":
-			return nativeBoolToBooleanObject(leftVal != float64(rightVal))
+			return nativeBoolToBooleanObject(leftVal != rightVal)
 
 		default:
 			return newError("unknown operator: %s %s %s",
@@ -1693,11 +2515,17 @@ func evalBangOperatorExpression(right Object) Object {
 func evalMinusPrefixOperatorExpression(right Object) Object {
 	switch right.Type() {
 	case INTEGER:
-		value := right.(*Integer).Value
-		return &Integer{Value: -value}
+		integer := right.(*Integer)
+		if integer.Big != nil || integer.Value == math.MinInt64 {
+			return NewBigInteger(new(big.Int).Neg(integer.BigInt()))
+		}
+		return &Integer{Value: -integer.Value}
 	case FLOAT:
 		value := right.(*Float).Value
 		return &Float{Value: -value}
+	case DECIMAL:
+		value := right.(*Decimal)
+		return &Decimal{Value: new(big.Int).Neg(value.Value), Scale: value.Scale}
 	}
 	return newError("unknown operator: -%s", right.Type())
 }
@@ -1708,6 +2536,8 @@ func evalPlusPrefixOperatorExpression(right Object) Object {
 		return right
 	case FLOAT:
 		return right
+	case DECIMAL:
+		return right
 	}
 	return newError("unknown operator: +%s", right.Type())
 }
@@ -1764,6 +2594,24 @@ func evalLoopInExpression(le *ast.LoopInExpression, env *Environment) Object {
 	if isError(loopRange) {
 		return loopRange
 	}
+	if it, ok := UnwrapReferenceValue(loopRange).(Iterator); ok {
+		for {
+			v, ok := it.Next(env)
+			if !ok {
+				return result
+			}
+			if isError(v) {
+				return v
+			}
+			newResult, done := evalLoopInBody(le, v, env)
+			if done {
+				return newResult
+			}
+			if newResult.Type() != JUMP {
+				result = newResult
+			}
+		}
+	}
 	if f, ok := env.Get("len"); ok {
 		length := applyCall(*f, []Object{loopRange}, env)
 		if isError(length) {
@@ -1771,26 +2619,16 @@ func evalLoopInExpression(le *ast.LoopInExpression, env *Environment) Object {
 		}
 
 		for i := int64(0); i < length.(*Integer).Value; i++ {
-			newEnv := env.NewEnclosedEnvironment()
 			v := applyIndex(loopRange, []Object{&Integer{Value: i}}, Default, env)
 			if isError(v) {
 				return v
 			}
-			if le.Name.Value[0] == '&' {
-				newEnv.SetCurrent(le.Name.Value, v)
-			} else {
-				newEnv.SetCurrent(le.Name.Value, UnwrapReferenceValue(v))
-			}
 
-			newResult := Eval(le.Body, newEnv)
-			if isError(newResult) || newResult.Type() == RET {
+			newResult, done := evalLoopInBody(le, v, env)
+			if done {
 				return newResult
 			}
 
-			if newResult.Type() == OUT {
-				return UnwrapOutValue(newResult)
-			}
-
 			if newResult.Type() != JUMP {
 				result = newResult
 			}
@@ -1800,6 +2638,27 @@ func evalLoopInExpression(le *ast.LoopInExpression, env *Environment) Object {
 	return newError("len")
 }
 
+// evalLoopInBody runs the body of le for v, and returns true when the loop
+// should stop with the result.
+func evalLoopInBody(le *ast.LoopInExpression, v Object, env *Environment) (Object, bool) {
+	newEnv := env.NewEnclosedEnvironment()
+	if le.Name.Value[0] == '&' {
+		newEnv.SetCurrent(le.Name.Value, v)
+	} else {
+		newEnv.SetCurrent(le.Name.Value, UnwrapReferenceValue(v))
+	}
+
+	newResult := Eval(le.Body, newEnv)
+	if isError(newResult) || newResult.Type() == RET {
+		return newResult, true
+	}
+
+	if newResult.Type() == OUT {
+		return UnwrapOutValue(newResult), true
+	}
+	return newResult, false
+}
+
 func isTruthy(obj Object) bool {
 	return toBoolean(obj) == TrueObj
 }
@@ -1807,7 +2666,7 @@ func isTruthy(obj Object) bool {
 func toBoolean(obj Object) Object {
 	switch obj.Type() {
 	case INTEGER:
-		if obj.(*Integer).Value != 0 {
+		if obj.(*Integer).Big != nil || obj.(*Integer).Value != 0 {
 			return TrueObj
 		}
 		return FalseObj
@@ -1816,6 +2675,11 @@ func toBoolean(obj Object) Object {
 			return TrueObj
 		}
 		return FalseObj
+	case DECIMAL:
+		if obj.(*Decimal).Value.Sign() != 0 {
+			return TrueObj
+		}
+		return FalseObj
 	case BOOLEAN:
 		return obj
 	default:
@@ -1843,8 +2707,14 @@ func getLen(obj Object, env *Environment) Object {
 	switch arg := UnwrapReferenceValue(obj).(type) {
 	case *String:
 		return &Integer{Value: int64(len(arg.Value))}
+	case *Bytes:
+		return &Integer{Value: int64(len(arg.Value))}
 	case *Array:
 		return &Integer{Value: int64(len(arg.Elements))}
+	case *Tuple:
+		return &Integer{Value: int64(len(arg.Elements))}
+	case *Set:
+		return &Integer{Value: int64(len(arg.Elements))}
 	case *Hash:
 		ref := applyIndex(arg, []Object{&String{Value: []rune("@len")}}, Default, env).(*Reference)
 		if ref.Value != nil {
@@ -3,7 +3,16 @@ package evaluator
 import (
 	"github.com/mark07x/TLang/lexer"
 	"github.com/mark07x/TLang/parser"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -33,11 +42,139 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1;", "9223372036854775808"},
+		{"-9223372036854775807 - 2;", "-9223372036854775809"},
+		{"4294967296 * 4294967296;", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1);", "9223372036854775808"},
+		{"let a = 1; let i = 0; loop (i < 30) { a *= 1000; i += 1; }; a;", "1" + strings.Repeat("000", 30)},
+		{"integer(\"123456789012345678901234567890\");", "123456789012345678901234567890"},
+		{"integer(\"123456789012345678901234567890\") % 1000;", "890"},
+		{"string(9223372036854775807 * 10);", "\"92233720368547758070\""},
+		{"9223372036854775807 * 10 / 10;", "9.223372036854776e+18"},
+		{"float(9223372036854775807 * 2);", "1.8446744073709552e+19"},
+		{"integer(1e20);", "100000000000000000000"},
+		{"99999999999999999999;", "99999999999999999999"},
+		{"99999999999999999999 - 99999999999999999998;", "1"},
+		{"-9223372036854775808 == -9223372036854775807 - 1;", "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("expected=%s, got=%s", tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	intTests := []struct {
+		input    string
+		expected int64
+	}{
+		{"9223372036854775807 + 1 - 2;", 9223372036854775806},
+		{"(9223372036854775807 + 1) * 0;", 0},
+		{"integer((9223372036854775807 + 10) > 9223372036854775807);", 1},
+		{"integer(9223372036854775807 + 10 == integer(\"9223372036854775817\"));", 1},
+		{"integer((9223372036854775807 + 10) > 1.5);", 1},
+		{"let a = {}; a[9223372036854775807 + 1] = 3; a[integer(\"9223372036854775808\")];", 3},
+	}
+
+	for _, tt := range intTests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+		if i, ok := evaluated.(*Integer); ok && i.Big != nil {
+			t.Errorf("integer should fit in int64: %s", tt.input)
+		}
+	}
+
+	testErrObject(t, testEval("5 % 0;"), "division by zero: 5 % 0")
+	testErrObject(t, testEval("[1, 2][99999999999999999999];"), "array: index is not a machine integer")
+	testErrObject(t, testEval("\"ab\"[-99999999999999999999];"), "string: index is not a machine integer")
+	testErrObject(t, testEval("[1, 2][::99999999999999999999];"), "slice: step is not a machine integer")
+	testErrObject(t, testEval("array(99999999999999999999);"), "native function array: args[0] should be Integer")
+
+	evaluated := testEval("let a = [1, 2, 3]; [a[1:99999999999999999999], a[-99999999999999999999:1]];")
+	if evaluated.Inspect(16, nil) != "[[2, 3], [1]]" {
+		t.Errorf("expected=%s, got=%s", "[[2, 3], [1]]", evaluated.Inspect(16, nil))
+	}
+}
+
+func TestDecimalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"decimal(\"0.1\") + decimal(\"0.2\");", "0.3"},
+		{"decimal(0.1) + 0.2;", "0.3"},
+		{"decimal(\"1.10\") + 1;", "2.10"},
+		{"decimal(\"1.5\") * decimal(\"1.5\");", "2.25"},
+		{"decimal(\"10\") - decimal(\"0.01\");", "9.99"},
+		{"decimal(1) / 4;", "0.25"},
+		{"decimal(1) / 3;", "0.3333333333333333"},
+		{"decimal(2) / 3;", "0.6666666666666667"},
+		{"decimal(\"7.5\") % 2;", "1.5"},
+		{"-decimal(\"1.25\");", "-1.25"},
+		{"decimal(\"1.2e3\");", "1200"},
+		{"decimal(\"12.5e-3\");", "0.0125"},
+		{"decimal(\"2.345\", 2);", "2.34"},
+		{"decimal(\"2.355\", 2);", "2.36"},
+		{"decimal(\"2.345\", 2, \"halfUp\");", "2.35"},
+		{"decimal(\"-2.341\", 2, \"floor\");", "-2.35"},
+		{"decimal(\"-2.349\", 2, \"ceiling\");", "-2.34"},
+		{"decimal(\"2.341\", 2, \"up\");", "2.35"},
+		{"decimalContext(2, \"down\"); decimal(2) / 3;", "0.66"},
+		{"decimalContext();", "[16, \"halfEven\"]"},
+		{"string(decimal(\"0.50\"));", "\"0.50\""},
+		{"integer(decimal(\"-2.9\"));", "-2"},
+		{"float(decimal(\"2.5\"));", "2.5"},
+		{"type(decimal(1));", "\"Decimal\""},
+		{"decimal(\"0.1\") + decimal(\"0.2\") == decimal(\"0.3\");", "true"},
+		{"decimal(\"0.30\") == 0.3;", "true"},
+		{"decimal(\"1.5\") > 1;", "true"},
+		{"2 < decimal(\"1.5\");", "false"},
+		{"{decimal(\"1.50\"): 1}[decimal(\"1.5\")];", "1"},
+		{"if (decimal(0)) { 1; } else { 2; };", "2"},
+		{"{decimal(\"1\"): 1}[1];", "1"},
+		{"{1: 1}[decimal(\"1.00\")];", "1"},
+		{"{99999999999999999999: 1}[decimal(\"99999999999999999999.0\")];", "1"},
+		{"decimal(\"1e10000\") > 0;", "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"decimal(\"abc\");", "could not parse abc as decimal"},
+		{"decimal(1) / 0;", "division by zero: 1 / 0"},
+		{"decimal(1) + \"a\";", "type mismatch: Decimal + String"},
+		{"decimal(1, 2, \"nearest\");", "native function decimal: args[2] should be rounding mode"},
+		{"decimal(\"1e999999999\");", "could not parse 1e999999999 as decimal: exponent out of range"},
+		{"decimal(\"1e-999999999\");", "could not parse 1e-999999999 as decimal: exponent out of range"},
+		{"decimal(1, 4294967297);", "native function decimal: args[1] should be Integer from 0 to 10000"},
+		{"decimalContext(-1);", "native function decimalContext: args[0] should be Integer from 0 to 10000"},
+		{"decimal(math.nan);", "could not convert NaN to decimal"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func testEval(input string) Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := SharedEnv.NewEnclosedEnvironment()
+	env := NewScriptEnvironment()
 
 	return Eval(program, env)
 }
@@ -392,6 +529,1098 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"bytes(\"hé\", \"utf-8\");", "b\"h\\xc3\\xa9\""},
+		{"bytes(\"hé\", \"latin-1\");", "b\"h\\xe9\""},
+		{"bytes(\"hé\", \"utf-16le\");", "b\"h\\x00\\xe9\\x00\""},
+		{"bytes(\"hé\", \"UTF-16BE\");", "b\"\\x00h\\x00\\xe9\""},
+		{"bytes([104, 105, 0, 34]);", "b\"hi\\x00\\\"\""},
+		{"bytes(3);", "b\"\\x00\\x00\\x00\""},
+		{"string(bytes([104, 195, 169]), \"utf-8\");", "\"hé\""},
+		{"string(bytes(\"hé\", \"utf-16be\"), \"utf-16be\");", "\"hé\""},
+		{"string(bytes([104, 233]), \"latin-1\");", "\"hé\""},
+		{"bytes([1, 2, 3])[1];", "2"},
+		{"bytes([1, 2, 3])[1:];", "b\"\\x02\\x03\""},
+		{"bytes([1]) + bytes([2]);", "b\"\\x01\\x02\""},
+		{"len(bytes(\"hé\", \"utf-8\"));", "3"},
+		{"bytes([1, 2]) == bytes([1, 2]);", "true"},
+		{"bytes([1, 2]) < bytes([1, 3]);", "true"},
+		{"let s = 0; loop b in (bytes([1, 2, 3])) { s += b; }; s;", "6"},
+		{"{bytes([1]): 2}[bytes([1])];", "2"},
+		{"let b = bytes(3); #C[\"memset\"](b, 65, 2); b;", "b\"\\x00\\x00\\x00\""},
+		{"let p = #C[\"malloc\"](3); #C[\"memset\"](p, 65, 3); let b = cdlBytes(p, 2); #C[\"free\"](p); b;", "b\"AA\""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"bytes(\"a\");", "native function bytes: encoding of String should be given"},
+		{"bytes(\"é\", \"ascii\");", "native function bytes: character 'é' at 0 can not be encoded in ascii"},
+		{"bytes(\"a\", \"ebcdic\");", "native function bytes: unknown encoding ebcdic"},
+		{"string(bytes([255]), \"utf-8\");", "native function string: invalid utf-8 data"},
+		{"bytes([256]);", "native function bytes: element 0 should be Integer in 0..255"},
+		{"bytes(9223372036854775807);", "native function bytes: length should be at most 1073741824"},
+		{"let b = bytes([1]); b[0] = 2;", "assign to const reference"},
+		{"bytes([1]) + \"a\";", "type mismatch: Bytes + String"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"\"a,b,c\".split(\",\");", "[\"a\", \"b\", \"c\"]"},
+		{"split(\"a,b,c\", \",\", 2);", "[\"a\", \"b,c\"]"},
+		{"\" a  b \".split();", "[\"a\", \"b\"]"},
+		{"join([1, \"a\", 'c'], \"-\");", "\"1-a-c\""},
+		{"\"  hi \\n\".trim();", "\"hi\""},
+		{"\"xxhixx\".trim(\"x\");", "\"hi\""},
+		{"\"xxhixx\".trimLeft(\"x\");", "\"hixx\""},
+		{"\"xxhixx\".trimRight(\"x\");", "\"xxhi\""},
+		{"\"héllo wörld\".find(\"wö\");", "6"},
+		{"\"héllo\".find(\"l\", 3);", "3"},
+		{"\"héllo\".find(\"z\");", "-1"},
+		{"\"héllo\".findLast(\"l\");", "3"},
+		{"\"ababa\".count(\"a\");", "3"},
+		{"\"aaa\".replace(\"a\", \"é\");", "\"ééé\""},
+		{"\"aaa\".replace(\"a\", \"b\", 2);", "\"bba\""},
+		{"\"Héllo\".upper();", "\"HÉLLO\""},
+		{"\"HÉLLO\".lower();", "\"héllo\""},
+		{"\"héllo\".startsWith(\"hé\");", "true"},
+		{"\"héllo\".endsWith(\"x\");", "false"},
+		{"\"é\".repeat(3);", "\"ééé\""},
+		{"\"7\".padLeft(3, \"0\");", "\"007\""},
+		{"\"é\".padRight(3);", "\"é  \""},
+		{"\"hé\".chars();", "['h', 'é']"},
+		{"let s = \"a b\"; s.split(\" \")[1].upper();", "\"B\""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"\"a\".nothing();", "string: method nothing not found"},
+		{"\"a\".repeat(-1);", "native function repeat: args[1] should be non-negative"},
+		{"\"ab\".repeat(9223372036854775807);", "native function repeat: result should be at most 268435456 characters"},
+		{"\"a\".padLeft(9223372036854775807);", "native function padLeft: args[1] should be at most 268435456"},
+		{"\"a\".padRight(268435457, \"0\");", "native function padRight: args[1] should be at most 268435456"},
+		{"join([{\"@string\": func() { error(\"boom\"); }}]);", "boom"},
+		{"join([{\"@string\": func() { 1; }}]);", "native function join: element 0 should give a String"},
+		{"split(1);", "native function split: args[0] should be String"},
+		{"\"a\".find();", "native function find: len(args) should be 2 to 3"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestPrototypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3].len();", "3"},
+		{"\"héllo\".len();", "5"},
+		{"[1, 2].join(\",\");", "\"1,2\""},
+		{"let a = [1, 2]; a.first() = 3; a;", "[3, 2]"},
+		{"(12).string();", "\"12\""},
+		{"tuple(1, 2).len();", "2"},
+		{"set(1).union(set(2));", "set(1, 2)"},
+		{"let h = {\"@class\": \"A\"}; h.classType();", "\"Proto\""},
+		{"let h = {}; h.len = 5; h.len;", "5"},
+		{"Array.sum = func(self) { let s = 0; loop e in (self) { s += e; }; ret s; }; [1, 2, 3].sum();", "6"},
+		{"String.twice = func(self) { ret self + self; }; \"ab\".twice();", "\"abab\""},
+		{"Integer.double = func(self) { ret self * 2; }; let n = 4; n.double();", "8"},
+		{"Array.pair = func(x, self) { ret [self[0], x]; }; [1].pair(2);", "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1].nothing();", "array: index should be Integer"},
+		{"(1).nothing();", "not Array, String or Hash: Integer"},
+		{"[1, 2].sum();", "array: index should be Integer"},
+		{"let h = {}; h.nothing();", "not a function, underline function or a native function: Void"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHigherOrderArrayFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], func(x) { ret x * 2; });", "[2, 4, 6]"},
+		{"[1, 2, 3].map(string);", "[\"1\", \"2\", \"3\"]"},
+		{"[1, 2, 3, 4].filter(func(x) { ret x % 2 == 0; });", "[2, 4]"},
+		{"[1, 2, 3].reduce(func(a, b) { ret a + b; });", "6"},
+		{"[1, 2, 3].reduce(func(a, b) { ret a + b; }, 10);", "16"},
+		{"[].reduce(func(a, b) { ret a + b; }, 10);", "10"},
+		{"[1, 2, 3].find(func(x) { ret x > 1; });", "2"},
+		{"[1, 2, 3].find(func(x) { ret x > 5; });", "void"},
+		{"find(\"abc\", \"c\");", "2"},
+		{"[0, 1].any();", "true"},
+		{"[0, 1].all();", "false"},
+		{"[1, 2].all(func(x) { ret x > 0; });", "true"},
+		{"[].any(func(x) { ret x > 0; });", "false"},
+		{"zip([1, 2, 3], [\"a\", \"b\"]);", "[[1, \"a\"], [2, \"b\"]]"},
+		{"[\"a\", \"b\"].enumerate();", "[[0, \"a\"], [1, \"b\"]]"},
+		{"[1, 2, 3].reverse();", "[3, 2, 1]"},
+		{"\"héllo\".reverse();", "\"olléh\""},
+		{"[1, [2, [3]]].flatten();", "[1, 2, [3]]"},
+		{"[1, [2, [3]]].flatten(-1);", "[1, 2, 3]"},
+		{"[3, 1, 2].sort();", "[1, 2, 3]"},
+		{"[\"b\", \"a\"].sort();", "[\"a\", \"b\"]"},
+		{"[3, 1, 2].sort(func(a, b) { ret a > b; });", "[3, 2, 1]"},
+		{"[[1, \"b\"], [0, \"x\"], [1, \"a\"]].sort(func(a, b) { ret a[0] < b[0]; });", "[[0, \"x\"], [1, \"b\"], [1, \"a\"]]"},
+		{"let a = [3, 1]; a.sort(); a;", "[3, 1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2].map(func(x) { ret x + \"a\"; });", "type mismatch: Integer + String"},
+		{"[1, 2].filter(1);", "native function filter: args[1] should be Functor"},
+		{"[].reduce(func(a, b) { ret a + b; });", "native function reduce: empty Array with no initial value"},
+		{"[1, \"a\"].sort();", "type mismatch: String < Integer"},
+		{"[2, 1].sort(func(a, b) { ret a < error(\"bad\"); });", "bad"},
+		{"map(1, string);", "native function map: args[0] should be Array"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMutatingArrayFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1]; push(a, 2, 3); a;", "[1, 2, 3]"},
+		{"let a = []; a.push([1]); a.push(2); a;", "[[1], 2]"},
+		{"let a = [1, 3]; insert(a, 1, 2); a;", "[1, 2, 3]"},
+		{"let a = [1]; a.insert(1, 2); a;", "[1, 2]"},
+		{"let a = [1, 2, 3]; [a.remove(1), a];", "[2, [1, 3]]"},
+		{"let a = [1, 2, 3]; [a.pop(), a];", "[3, [1, 2]]"},
+		{"let a = [1, 2]; a.clear(); a;", "[]"},
+		{"let a = [1]; let &b = a; push(&b, 2); a;", "[1, 2]"},
+		{"let a = [[1]]; push(a[0], 2); a;", "[[1, 2]]"},
+		{"let f = func(&x) { push(&x, 1); }; let a = []; f(a); a;", "[1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"push([1], 2);", "native function push: assign to const reference"},
+		{"let f = func(&x) { push(&x, 1); }; f([]);", "native function push: assign to const reference"},
+		{"let &a = [1]; push(&a, 2);", "native function push: assign to const reference"},
+		{"let a = \"x\"; push(a, 1);", "native function push: args[0] should be Array"},
+		{"let a = []; a.pop();", "native function pop: empty Array"},
+		{"let a = [1]; a.remove(1);", "native function remove: out of range"},
+		{"let a = [1]; a.insert(2, 0);", "native function insert: out of range"},
+		{"push();", "native function push: len(args) should be at least 1"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHashFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let h = {\"b\": 2, \"a\": 1}; keys(h);", "[\"b\", \"a\"]"},
+		{"let h = {10: \"x\", 9: \"y\"}; h.keys();", "[10, 9]"},
+		{"let h = {\"b\": 2, \"a\": 1}; h.values();", "[2, 1]"},
+		{"let h = {\"b\": 2, \"a\": 1}; h.entries();", "[[\"b\", 2], [\"a\", 1]]"},
+		{"let h = {\"b\": 2, \"a\": 1}; h[\"c\"] = 3; h.remove(\"b\"); h[\"b\"] = 4; h;", "{ \"a\": 1, \"c\": 3, \"b\": 4 }"},
+		{"let h = {\"a\": 1}; [h.has(\"a\"), h.has(\"b\")];", "[true, false]"},
+		{"let h = {}; h.x; h.has(\"x\");", "false"},
+		{"let A = {\"@class\": \"A\", \"f\": 1}; let a = {\"@template\": A, \"x\": 2}; [has(a, \"f\"), hasOwn(a, \"f\"), hasOwn(a, \"x\"), keys(a)];", "[true, false, true, [\"x\"]]"},
+		{"let h = {\"a\": 1, \"b\": 2}; [h.remove(\"a\"), h.remove(\"z\"), h];", "[1, void, { \"b\": 2 }]"},
+		{"let h = {}; let i = 0; loop (i < 1000) { h[i] = i; i += 1; }; i = 0; loop (i < 997) { h.remove(i); i += 1; }; h[5] = 5; h.remove(998); h[0] = 0; keys(h);", "[997, 999, 5, 0]"},
+		{"let a = [1, 2]; a.remove(0); a;", "[2]"},
+		{"let h = {\"a\": 1, \"b\": 2}; merge(h, {\"b\": 3}, {\"c\": 4}).entries();", "[[\"a\", 1], [\"b\", 3], [\"c\", 4]]"},
+		{"let h = {\"a\": 1}; let m = h.merge({\"b\": 2}); h.keys();", "[\"a\"]"},
+		{"let h = {\"a\": [1]}; let c = h.clone(); push(c.a, 2); [h.a, c.a];", "[[1], [1, 2]]"},
+		{"let A = {\"@class\": \"A\", \"f\": 1}; let a = {\"@template\": A}; let c = clone(a); c.f;", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"keys(1);", "native function keys: args[0] should be Hash"},
+		{"let h = {}; h.has([1]);", "native function has: unusable as hash key: Array"},
+		{"let &h = {\"a\": 1}; remove(&h, \"a\");", "native function remove: assign to const reference"},
+		{"let h = {}; merge(h, 1);", "native function merge: args[1] should be Hash"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"math.sqrt(9);", "3"},
+		{"math.sqrt(2.25);", "1.5"},
+		{"math.pow(2, 10);", "1024"},
+		{"math.pow(2, 100);", "1267650600228229401496703205376"},
+		{"math.pow(2, -1);", "0.5"},
+		{"math.pow(4, 0.5);", "2"},
+		{"type(math.pow(2, 3));", "\"Integer\""},
+		{"math.floor(2.7);", "2"},
+		{"math.ceil(2.1);", "3"},
+		{"math.round(-2.5);", "-3"},
+		{"math.trunc(-2.7);", "-2"},
+		{"math.floor(5);", "5"},
+		{"[math.floor(decimal(\"-2.5\")), math.ceil(decimal(\"2.1\")), math.round(decimal(\"2.5\")), math.trunc(decimal(\"-2.7\"))];", "[-3, 3, 3, -2]"},
+		{"type(math.round(decimal(\"2.5\")));", "\"Decimal\""},
+		{"[math.pow(1, 99999999999999999999), math.pow(-1, 10000000001), math.pow(0, 10000000000)];", "[1, -1, 0]"},
+		{"math.pow(2, 1048576) > 0;", "true"},
+		{"math.abs(-3);", "3"},
+		{"math.abs(-3.5);", "3.5"},
+		{"math.abs(math.minInt);", "9223372036854775808"},
+		{"math.abs(decimal(\"-1.50\"));", "1.50"},
+		{"math.max(1, 3.5, 2);", "3.5"},
+		{"math.min([3, 1, 2]);", "1"},
+		{"math.max([]);", "void"},
+		{"math.log(math.e);", "1"},
+		{"math.log(8, 2);", "3"},
+		{"math.log10(1000);", "3"},
+		{"math.sin(0);", "0"},
+		{"math.atan2(1, 1) * 4 == math.pi;", "true"},
+		{"math.hypot(3, 4);", "5"},
+		{"math.isNaN(math.nan);", "true"},
+		{"math.isInf(-math.inf);", "true"},
+		{"#.sqrt(16);", "4"},
+		{"#.max(1, 2);", "2"},
+		{"#.min([4, 3]);", "3"},
+		{"#.abs(-1);", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"math.sqrt(\"a\");", "native function math.sqrt: args[0] should be Integer or Float"},
+		{"math.sqrt(1, 2);", "native function math.sqrt: len(args) should be 1"},
+		{"math.max(1, \"a\");", "native function math.max: type mismatch: String > Integer"},
+		{"math.pow(2, 10000000000);", "native function math.pow: result has more than 1048576 bits"},
+		{"math.pow(-3, 99999999999999999999);", "native function math.pow: result has more than 1048576 bits"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRandomModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"random.new(42);", "random(42)"},
+		{"let a = random.new(7); let b = random.new(7); array(20, 0, func(i, p) { ret a.int(100); }) == array(20, 0, func(i, p) { ret b.int(100); });", "true"},
+		{"let a = random.new(7); let b = random.new(8); array(20, 0, func(i, p) { ret a.int(1000000); }) == array(20, 0, func(i, p) { ret b.int(1000000); });", "false"},
+		{"let g = random.new(1); array(100, 0, func(i, p) { ret g.int(5, 8); }).all(func(x) { ret x >= 5 and x < 8; });", "true"},
+		{"let g = random.new(1); array(100, 0.0, func(i, p) { ret g.float(); }).all(func(x) { ret x >= 0 and x < 1; });", "true"},
+		{"let g = random.new(1); array(100, 0.0, func(i, p) { ret g.float(-2, 2); }).all(func(x) { ret x >= -2 and x < 2; });", "true"},
+		{"let g = random.new(3); 7 in array(20, 0, func(i, p) { ret g.choice([7, 8]); });", "true"},
+		{"let g = random.new(3); g.shuffle([1, 2, 3, 4, 5]).sort();", "[1, 2, 3, 4, 5]"},
+		{"let g = random.new(3); let a = [1, 2, 3]; g.shuffle(a); a;", "[1, 2, 3]"},
+		{"let g = random.new(3); len(g.sample([1, 2, 3, 4, 5], 3));", "3"},
+		{"let g = random.new(3); g.sample([1, 2, 3, 4, 5], 5).sort();", "[1, 2, 3, 4, 5]"},
+		{"let g = random.new(3); random.int(g, 1, 2);", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"random.new();", "native function random.new: len(args) should be 1"},
+		{"random.new(1).int(3, 3);", "native function random.int: empty range [3, 3)"},
+		{"random.new(1).choice([]);", "native function random.choice: empty Array"},
+		{"random.new(1).sample([1], 2);", "native function random.sample: sample size 2 out of range"},
+		{"random.int(1, 2);", "native function random.int: args[0] should be Random"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTimeModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"time(\"2024-02-29T12:30:00Z\");", "time(\"2024-02-29T12:30:00Z\")"},
+		{"time.date(2024, 2, 29, 12, 30);", "time(\"2024-02-29T12:30:00Z\")"},
+		{"time.date(2024, 2, 29, 12, 30, 0, 0, \"Asia/Shanghai\");", "time(\"2024-02-29T12:30:00+08:00\")"},
+		{"time.parse(\"DateTime\", \"2024-02-29 12:30:00\");", "time(\"2024-02-29T12:30:00Z\")"},
+		{"time.parse(\"2006/01/02\", \"2024/03/01\", \"America/New_York\").format(\"RFC3339\");", "\"2024-03-01T00:00:00-05:00\""},
+		{"time(\"2024-02-29T12:30:00Z\").format(\"Jan 2, 2006 3:04PM\");", "\"Feb 29, 2024 12:30PM\""},
+		{"time(\"2024-02-29T12:30:00Z\").inZone(\"Asia/Tokyo\").hour();", "21"},
+		{"let t = time(\"2024-02-29T12:30:05Z\"); [t.year(), t.month(), t.day(), t.minute(), t.second(), t.weekday(), t.yearDay()];", "[2024, 2, 29, 30, 5, \"Thursday\", 60]"},
+		{"time(\"2024-02-29T23:00:00Z\") + 3600;", "time(\"2024-03-01T00:00:00Z\")"},
+		{"time(\"2024-03-01T00:00:00Z\") - 0.5;", "time(\"2024-02-29T23:59:59.5Z\")"},
+		{"time(\"2024-03-01T00:00:00Z\") - time(\"2024-02-29T00:00:00Z\");", "86400"},
+		{"time(\"2024-03-01T00:00:00Z\") > time(\"2024-02-29T00:00:00Z\");", "true"},
+		{"time(\"2024-03-01T09:00:00+09:00\") == time(\"2024-03-01T00:00:00Z\");", "true"},
+		{"time(\"2024-03-01T00:00:00Z\") == 1;", "false"},
+		{"time(\"2024-03-01T00:00:00Z\") in [time(\"2024-03-01T00:00:00Z\")];", "true"},
+		{"let h = {time(\"2024-03-01T09:00:00+09:00\"): 1}; h[time(\"2024-03-01T00:00:00Z\")];", "1"},
+		{"time.unix(1709251200);", "time(\"2024-03-01T00:00:00Z\")"},
+		{"time(\"2024-03-01T00:00:00Z\").unix();", "1.7092512e+09"},
+		{"let a = time.monotonic(); time.sleep(0.01); time.monotonic() - a >= 0.01;", "true"},
+		{"time.now() - time.now() <= 0;", "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"time();", "native function time: len(args) should be 1"},
+		{"time[\"@()\"]();", "native function time: len(args) should be 1"},
+		{"time(\"yesterday\");", "native function time: parsing time \"yesterday\" as \"2006-01-02T15:04:05.999999999Z07:00\": cannot parse \"yesterday\" as \"2006\""},
+		{"time.parse(\"DateOnly\", \"2024-13-01\");", "native function time.parse: parsing time \"2024-13-01\": month out of range"},
+		{"time.date(2024, 1, 1, 0, 0, 0, 0, \"Mars/Base\");", "native function time.date: unknown time zone Mars/Base"},
+		{"time.now() + \"a\";", "type mismatch: Time + String"},
+		{"time.now() < 1;", "type mismatch: Time < Integer"},
+		{"time.now() * 2;", "unknown operator: Time * Integer"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFsModule(t *testing.T) {
+	dir := t.TempDir()
+	q := func(input string) string {
+		return strings.ReplaceAll(input, "DIR", strings.ReplaceAll(dir, "\\", "\\\\"))
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fs.write(\"DIR/a.txt\", \"héllo\\n\"); fs.read(\"DIR/a.txt\");", "\"héllo\\n\""},
+		{"fs.append(\"DIR/a.txt\", bytes([119])); fs.readBytes(\"DIR/a.txt\");", "b\"h\\xc3\\xa9llo\\x0aw\""},
+		{"let f = fs.open(\"DIR/a.txt\"); [f.readLine(), f.readLine(), f.readLine()];", "[\"héllo\", \"w\", void]"},
+		{"let f = fs.open(\"DIR/a.txt\"); [f.readBytes(1), f.read()];", "[b\"h\", \"éllo\\nw\"]"},
+		{"let f = fs.open(\"DIR/a.txt\"); [f.readBytes(3), f.readBytes(1073741824), f.readBytes(1)];", "[b\"h\\xc3\\xa9\", b\"llo\\x0aw\", b\"\"]"},
+		{"let f = fs.open(\"DIR/b.txt\", \"w\"); f.write(\"1\"); f.write(\"2\"); f.close(); fs.read(\"DIR/b.txt\");", "\"12\""},
+		{"let f = fs.open(\"DIR/b.txt\", \"a\"); f.write(\"3\"); f.close(); fs.read(\"DIR/b.txt\");", "\"123\""},
+		{"fs.mkdir(\"DIR/x/y\", true); fs.list(\"DIR\");", "[\"a.txt\", \"b.txt\", \"x\"]"},
+		{"let s = fs.stat(\"DIR/b.txt\"); [s.name, s.size, s.isDir, type(s.modified)];", "[\"b.txt\", 3, false, \"Time\"]"},
+		{"fs.rename(\"DIR/b.txt\", \"DIR/x/c.txt\"); [fs.exists(\"DIR/b.txt\"), fs.exists(\"DIR/x/c.txt\")];", "[false, true]"},
+		{"fs.remove(\"DIR/x\", true); fs.exists(\"DIR/x\");", "false"},
+		{"try(fs.read, \"DIR/none\")[0];", "void"},
+		{"try(fs.read, \"DIR/a.txt\");", "[\"héllo\\nw\", void]"},
+		{"try(func() { ret 1 + \"a\"; });", "[void, \"type mismatch: Integer + String\"]"},
+		{"fs.join(\"a\", \"b/../c\", \"d.t\");", "\"a/c/d.t\""},
+		{"fs.clean(\"a//b/./c/..\");", "\"a/b\""},
+		{"[fs.base(\"a/b.t\"), fs.dir(\"a/b.t\"), fs.ext(\"a/b.t\")];", "[\"b.t\", \"a\", \".t\"]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(q(tt.input))
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fs.read(\"DIR/none\");", "native function fs.read: open DIR/none: no such file or directory"},
+		{"fs.open(\"DIR/a.txt\", \"x\");", "native function fs.open: unknown mode x"},
+		{"fs.open('a');", "native function fs.open: open a: no such file or directory"},
+		{"let f = fs.open(\"DIR/a.txt\"); f.close(); f.read();", "native function file.read: file already closed"},
+		{"let f = fs.open(\"DIR/a.txt\"); f.write(\"x\");", "native function file.write: file not opened for writing"},
+		{"fs.open(\"DIR/a.txt\").readBytes(9223372036854775807);", "native function file.readBytes: args[1] should be at most 1073741824"},
+		{"fs.write(\"DIR/a.txt\", 1);", "native function fs.write: args[1] should be String or Bytes"},
+		{"fs.mkdir(\"DIR/a.txt\");", "native function fs.mkdir: mkdir DIR/a.txt: file exists"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(q(tt.input)), q(tt.expected))
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	defer func(s *Sandbox) { DefaultSandbox = s }(DefaultSandbox)
+	dir := t.TempDir()
+	q := func(input string) string {
+		return strings.ReplaceAll(input, "DIR", strings.ReplaceAll(dir, "\\", "\\\\"))
+	}
+
+	DefaultSandbox = NewSandbox()
+	testEval(q("sandbox.confine(\"DIR/in\");"))
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fs.read(\"DIR/secret\");", "native function fs.read: permission denied: DIR/secret is outside DIR/in"},
+		{"fs.write(\"DIR/in/../secret\", \"x\");", "native function fs.write: permission denied: DIR/in/../secret is outside DIR/in"},
+		{"import(\"DIR/x.t\");", "native function import: permission denied: DIR/x.t is outside DIR/in"},
+		{"sandbox.confine(\"DIR\");", "native function sandbox.confine: permission denied"},
+		{"sandbox.drop(\"write\"); fs.write(\"DIR/in/a\", \"x\");", "native function fs.write: permission denied: write"},
+		{"sandbox.drop(\"exe\");", "native function sandbox.drop: unknown capability exe"},
+		{"sandbox.drop(\"ffi\"); cdlOpen(\"libc.so.6\");", "native function cdlOpen: permission denied: ffi"},
+		{"cdlSym(0, \"system\");", "native function cdlSym: permission denied: ffi"},
+		{"cdlBytes(0, 0);", "native function cdlBytes: permission denied: ffi"},
+		{"cdlCall(0, [], [], \"void\");", "native function cdlCall: permission denied: ffi"},
+		{"sandbox.drop(\"read\", \"writes\");", "native function sandbox.drop: unknown capability writes"},
+		{"sandbox.allowed(\"bogus\");", "native function sandbox.allowed: unknown capability bogus"},
+	}
+	testEval(q("fs.mkdir(\"DIR/in\");"))
+	for _, tt := range errTests {
+		testErrObject(t, testEval(q(tt.input)), q(tt.expected))
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sandbox.allowed(\"write\");", "false"},
+		{"sandbox.allowed(\"read\");", "true"},
+		{"sandbox.allowed(\"exec\");", "true"},
+		{"fs.exists(\"DIR/in/a\");", "false"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(q(tt.input))
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+}
+
+func TestOsModule(t *testing.T) {
+	defer SetArgs("", nil)
+	SetArgs("main.t", []string{"-n", "3", "--verbose", "in.txt", "--name=x", "--", "-z"})
+	_ = os.Setenv("TLANG_TEST_VAR", "1")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"os.script;", "\"main.t\""},
+		{"os.args;", "[\"-n\", \"3\", \"--verbose\", \"in.txt\", \"--name=x\", \"--\", \"-z\"]"},
+		{"os.getenv(\"TLANG_TEST_VAR\");", "\"1\""},
+		{"os.getenv(\"TLANG_TEST_NONE\");", "void"},
+		{"os.setenv(\"TLANG_TEST_VAR\", \"é\"); os.getenv(\"TLANG_TEST_VAR\");", "\"é\""},
+		{"os.environ()[\"TLANG_TEST_VAR\"];", "\"é\""},
+		{"os.unsetenv(\"TLANG_TEST_VAR\"); os.getenv(\"TLANG_TEST_VAR\");", "void"},
+		{"let f = os.parseFlags({\"n\": 1, \"verbose\": false, \"name\": \"\"}); [f.options.n, f.options.verbose, f.options.name, f.args];", "[3, true, \"x\", [\"in.txt\", \"-z\"]]"},
+		{"let f = os.parseFlags({\"n\": 1, \"r\": 0.5, \"v\": true}, [\"-v=false\", \"-r\", \"2\"]); [f.options.n, f.options.r, f.options.v, f.args];", "[1, 2, false, []]"},
+		{"let f = os.parseFlags({\"n\": 1}, [\"-5\", \"-n\", \"-2\", \"-\", \"-0.5\"]); [f.options.n, f.args];", "[-2, [\"-5\", \"-\", \"-0.5\"]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"os.parseFlags({\"n\": 1}, [\"-m\"]);", "native function os.parseFlags: unknown flag -m"},
+		{"os.parseFlags({\"n\": 1}, [\"---n\", \"2\"]);", "native function os.parseFlags: unknown flag ---n"},
+		{"os.parseFlags({\"n\": 1}, [\"-n\", \"x\"]);", "native function os.parseFlags: flag n should be Integer: x"},
+		{"os.parseFlags({\"n\": 1}, [\"-n\"]);", "native function os.parseFlags: flag -n needs a value"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+
+	defer func(s *Sandbox) { DefaultSandbox = s }(DefaultSandbox)
+	DefaultSandbox = NewSandbox()
+	DefaultSandbox.Drop(CapEnv)
+	testErrObject(t, testEval("os.getenv(\"HOME\");"), "native function os.getenv: permission denied: env")
+}
+
+func TestProcessModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"process.run([\"echo\", \"hi\", \"é\"]).stdout;", "\"hi é\\n\""},
+		{"let r = process.run([\"sh\", \"-c\", \"echo out; echo err >&2; exit 3\"]); [r.stdout, r.stderr, r.code, r.timedOut];", "[\"out\\n\", \"err\\n\", 3, false]"},
+		{"process.run([\"cat\"], {\"stdin\": \"abc\"}).stdout;", "\"abc\""},
+		{"process.run([\"cat\"], {\"stdin\": bytes([104, 105])}).stdout;", "\"hi\""},
+		{"let r = process.run([\"cat\"], {\"stdin\": bytes([255, 0, 1])}); [r.stdoutBytes, r.stderrBytes];", "[b\"\\xff\\x00\\x01\", b\"\"]"},
+		{"process.run([\"pwd\"], {\"dir\": \"/\"}).stdout;", "\"/\\n\""},
+		{"process.run([\"sh\", \"-c\", \"echo $TLANG_X\"], {\"env\": {\"TLANG_X\": \"y\"}}).stdout;", "\"y\\n\""},
+		{"let r = process.run([\"sleep\", \"5\"], {\"timeout\": 0.05}); [r.timedOut, r.code];", "[true, -1]"},
+		{"process.run([\"true\"], {\"timeout\": 5}).timedOut;", "false"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"process.run([\"/nonexistent/cmd\"]);", "native function process.run: fork/exec /nonexistent/cmd: no such file or directory"},
+		{"process.run([]);", "native function process.run: empty command"},
+		{"process.run([\"echo\"], {\"cwd\": \"/\"});", "native function process.run: unknown option cwd"},
+		{"process.run([\"echo\"], {\"env\": {\"A\": 1}});", "native function process.run: env should be Hash of String"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+
+	defer func(s *Sandbox) { DefaultSandbox = s }(DefaultSandbox)
+	DefaultSandbox = NewSandbox()
+	DefaultSandbox.Drop(CapExec)
+	testErrObject(t, testEval("process.run([\"echo\"]);"), "native function process.run: permission denied: exec")
+}
+
+func TestJsonModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json.encode({"b": [1, 2.0, "x\n"], "a": {"v": void, "t": true}});`, `"{\"b\":[1,2.0,\"x\\n\"],\"a\":{\"v\":null,\"t\":true}}"`},
+		{`json.encode([1, {"a": []}], 2);`, `"[\n  1,\n  {\n    \"a\": []\n  }\n]"`},
+		{`json.encode({1: tuple(1, 2), "@class": "A"}, "");`, `"{\"1\":[1,2]}"`},
+		{`json.encode(math.pow(10, 30));`, `"1000000000000000000000000000000"`},
+		{`let P = {"@class": "P", "@json": func(self) { let o = {"name": self.name}; o; }}; json.encode({"@template": P, "name": "m", "age": 3});`, `"{\"name\":\"m\"}"`},
+		{`json.decode("{\"z\": 1, \"a\": [2.5, 1e3, \"\\u00e9\\ud83d\\ude00\", null, false]}");`, `{ "z": 1, "a": [2.5, 1000, "é😀", void, false] }`},
+		{`json.decode("123456789012345678901234567890");`, `123456789012345678901234567890`},
+		{`json.decode(json.encode({"a": [1, "b", {}]}))["a"];`, `[1, "b", {  }]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`let S = {"@class": "S", "@json": func(self) { self; }}; json.encode({"k": {"@template": S}});`, "native function json.encode: cycle at $.k"},
+		{`json.encode([1, math.nan]);`, "native function json.encode: unsupported value NaN at $[1]"},
+		{`json.encode({"f": print});`, "native function json.encode: unsupported type Native at $.f"},
+		{`json.decode("[1, 2");`, "native function json.decode: unexpected end of input at line 1, column 6"},
+		{`json.decode("{\"a\":\n 1,}");`, "native function json.decode: unexpected character '}' at line 2, column 4"},
+		{`json.decode("[1] x");`, "native function json.decode: unexpected character 'x' at line 1, column 5"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRegexModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex("a+");`, `regex("a+")`},
+		{`let re = regex("(\\w)(\\d+)"); [re.match("a12"), re.match("ab"), re.find("x b34 c5"), re.find("x")];`, `[true, false, "b34", void]`},
+		{`let re = regex("\\w\\d+"); [re.findAll("a1 b22 c333"), re.findAll("a1 b22 c333", 2)];`, `[["a1", "b22", "c333"], ["a1", "b22"]]`},
+		{`let re = regex("(\\w)(\\d)?"); [re.groups("-q"), re.groups("-"), re.groupsAll("a1 b")];`, `[["q", "q", void], void, [["a1", "a", "1"], ["b", "b", void]]]`},
+		{`regex("(?P<y>\\d{4})-(?P<m>\\d{2})").named("on 2024-02");`, `{ "y": "2024", "m": "02" }`},
+		{`regex("(\\w)(\\d+)").replace("a1 b22", "$2$1");`, `"1a 22b"`},
+		{`regex("(\\w)(\\d+)").replace("a1 b22", func(g) { g[2] + g[1]; });`, `"1a 22b"`},
+		{`regex("\\d").replace("a1", func(g) { len(g); });`, `"a1"`},
+		{`[regex(",\\s*").split("a, b,c"), regex(",").split("a,b,c", 2)];`, `[["a", "b", "c"], ["a", "b,c"]]`},
+		{`regex.match(regex.compile(regex.quote("a.b")), "a.b");`, `true`},
+		{`regex("\\d+").pattern();`, `"\\d+"`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex();`, "native function regex: len(args) should be 1"},
+		{`regex["@()"]();`, "native function regex: len(args) should be 1"},
+		{`regex("(a");`, "native function regex: error parsing regexp: missing closing ): `(a`"},
+		{`regex.compile("a**");`, "native function regex.compile: error parsing regexp: invalid nested repetition operator: `**`"},
+		{`regex("a").replace("a", 1);`, "native function regex.replace: args[2] should be String or Functor"},
+		{`regex("a").replace("a", func(g) { error("no"); });`, "no"},
+		{`regex.find("a", "a");`, "native function regex.find: args[0] should be Regex"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestCsvModule(t *testing.T) {
+	dir := t.TempDir()
+	q := func(input string) string {
+		return strings.ReplaceAll(input, "DIR", strings.ReplaceAll(dir, "\\", "\\\\"))
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`csv.parse("a,b\n1,\"x,\"\"y\"\"\"\n2\n");`, `[["a", "b"], ["1", "x,\"y\""], ["2"]]`},
+		{`csv.parse("name;age\n# skip\nm; 3\n", {"delimiter": ";", "comment": "#", "trim": true, "header": true});`, `[{ "name": "m", "age": "3" }]`},
+		{`csv.format([["a", "b c"], [1, "x,y"], [void, " q\""]]);`, `"a,b c\n1,\"x,y\"\n,\" q\"\"\"\n"`},
+		{`csv.format([tuple("a", "b")], {"delimiter": "\t", "quoteAll": true, "crlf": true});`, `"\"a\"\t\"b\"\r\n"`},
+		{`csv.format([{"n": "m", "a": 3}, {"a": 4}]);`, `"n,a\nm,3\n,4\n"`},
+		{`csv.format([{"a": 1}, {"a": 2, "b": 3}, {"c": 4}]);`, `"a,b,c\n1,,\n2,3,\n,,4\n"`},
+		{`csv.write("DIR/a.csv", [{"n": "m", "a": 3}, {"n": "k", "a": 4}]); csv.read("DIR/a.csv", {"header": true});`, `[{ "n": "m", "a": "3" }, { "n": "k", "a": "4" }]`},
+		{`let total = 0; loop row in (csv.reader("DIR/a.csv", {"header": true})) { total += row.a.integer(); }; total;`, `7`},
+		{`let r = csv.reader("DIR/a.csv"); [r.next(), r.next(), r.next(), r.next()];`, `[["n", "a"], ["m", "3"], ["k", "4"], void]`},
+		{`let r = csv.reader("DIR/a.csv"); r.close(); let n = 0; loop row in (r) { n += 1; }; n;`, `0`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(q(tt.input))
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`csv.parse("a,\"b\n");`, "native function csv.parse: parse error on line 1, column 6: extraneous or missing \" in quoted-field"},
+		{`csv.parse("a,b\n1\n", {"header": true});`, "native function csv.parse: record on line 2: wrong number of fields"},
+		{`csv.parse("a", {"quote": "'"});`, "native function csv.parse: unknown option quote"},
+		{`csv.parse("a", {"delimiter": ";;"});`, "native function csv.parse: delimiter should be a single character"},
+		{`csv.format([[1], {"a": 1}]);`, "native function csv.format: rows should all be Arrays or all be Hashes"},
+		{`loop row in (csv.reader("DIR/none.csv")) { row; };`, "native function csv.reader: open DIR/none.csv: no such file or directory"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(q(tt.input)), q(tt.expected))
+	}
+}
+
+func TestCodecModules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`base64.encode("héllo");`, `"aMOpbGxv"`},
+		{`string(base64.decode("aMOpbGxv"), "utf-8");`, `"héllo"`},
+		{`[base64.decode("YQ=="), base64.decode("YQ")];`, `[b"a", b"a"]`},
+		{`[base64.urlEncode(bytes([251, 255])), base64.urlDecode("-_8")];`, `["-_8=", b"\xfb\xff"]`},
+		{`[hex.encode(bytes([0, 171, 255])), hex.decode("00abFF")];`, `["00abff", b"\x00\xab\xff"]`},
+		{`digest.md5("abc");`, `"900150983cd24fb0d6963f7d28e17f72"`},
+		{`digest.sha1("abc");`, `"a9993e364706816aba3e25717850c26c9cd0d89d"`},
+		{`digest.sha256(bytes("abc", "utf-8"));`, `"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"`},
+		{`digest.crc32("The quick brown fox jumps over the lazy dog");`, `1095738169`},
+		{`digest.hmac("sha256", "key", "The quick brown fox jumps over the lazy dog");`, `"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`base64.decode("a$");`, "native function base64.decode: illegal base64 data at input byte 1"},
+		{`hex.decode("abc");`, "native function hex.decode: encoding/hex: odd length hex string"},
+		{`digest.sha256(1);`, "native function digest.sha256: args[0] should be String or Bytes"},
+		{`digest.hmac("sha512", "k", "d");`, "native function digest.hmac: unknown hash function sha512"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHttpModule(t *testing.T) {
+	server := `let s = http.listen({
+	"GET /hello": func(req) { "hi " + req.query.name; },
+	"POST /echo": func(req) { let o = {"status": 201, "headers": {"X-A": "1"}, "body": req.body + "!"}; o; },
+	"/files/": func(req) { req.method + " " + req.path; },
+	"/none": func(req) { void; },
+	"/bad": func(req) { error("boom"); },
+	"/odd": func(req) { 1; },
+	"/raw": func(req) { req.bodyBytes + bytes([0]); },
+}); let u = s.url(); `
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let r = http.get(u + "/hello?name=m"); let v = [r.status, r.body, r.headers["Content-Type"]];`, `[200, "hi m", "text/plain; charset=utf-8"]`},
+		{`let r = http.post(u + "/echo", "x", {"headers": {"Content-Type": "text/plain"}}); let v = [r.status, r.headers["X-A"], r.body];`, `[201, "1", "x!"]`},
+		{`let v = [http.get(u + "/files/a/b").body, http.request("delete", u + "/files/").body];`, `["GET /files/a/b", "DELETE /files/"]`},
+		{`let v = [http.get(u + "/none").status, http.get(u + "/nope").status, http.post(u + "/hello", "").status];`, `[204, 404, 405]`},
+		{`let r = http.get(u + "/bad"); let v = [r.status, r.body];`, `[500, "boom"]`},
+		{`let v = http.post(u + "/raw", bytes([255, 1])).bodyBytes;`, `b"\xff\x01\x00"`},
+		{`let v = http.get(u + "/odd").body;`, `"native function http: response should be String, Bytes, Hash or void, got Integer"`},
+		{`s.close(); s.wait(); let v = try(http.get, u)[0];`, `void`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(server + tt.input + ` s.close(); v;`)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`http.listen({"hello": func(req) { 1; }});`, `native function http.listen: route should be like "GET /path": hello`},
+		{`http.listen({"/": 1});`, "native function http.listen: handler of / should be Functor"},
+		{`http.get("http://127.0.0.1:1", {"retry": 1});`, "native function http.get: unknown option retry"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+
+	// handlers run one at a time while requests are made concurrently
+	s, ok := testEval(`let n = 0; http.listen({"/": func(req) { n += 1; string(n); }});`).(*Server)
+	if !ok {
+		t.Fatalf("http.listen should return a Server")
+	}
+	defer s.Server.Close()
+	seen := map[string]bool{}
+	blocking(func() {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := http.Get("http://" + s.Listener.Addr().String())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer resp.Body.Close()
+				body, _ := ioutil.ReadAll(resp.Body)
+				mu.Lock()
+				seen[string(body)] = true
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	})
+	if len(seen) != 20 || !seen["1"] || !seen["20"] {
+		t.Errorf("handlers should see 1 to 20 once each, got %v", seen)
+	}
+
+	defer func(s *Sandbox) { DefaultSandbox = s }(DefaultSandbox)
+	DefaultSandbox = NewSandbox()
+	DefaultSandbox.Drop(CapNet)
+	testErrObject(t, testEval(`http.get("http://127.0.0.1:1");`), "native function http.get: permission denied: net")
+}
+
+// TestHttpFromProcess calls a server of the script from a process it runs,
+// the test binary itself running TestHelperHttpGet.
+func TestHttpFromProcess(t *testing.T) {
+	input := `let s = http.listen({"/hello": func(req) { "hi"; }});
+let r = process.run([ARGV0, "-test.run=TestHelperHttpGet"], {"env": {"TLANG_HELPER_URL": s.url() + "/hello"}, "timeout": 10});
+s.close();
+[r.stdout, r.timedOut];`
+	input = strings.ReplaceAll(input, "ARGV0", strconv.Quote(os.Args[0]))
+	evaluated := testEval(input)
+	if evaluated.Inspect(16, nil) != `["hi", false]` {
+		t.Errorf("expected=%s, got=%s", `["hi", false]`, evaluated.Inspect(16, nil))
+	}
+}
+
+func TestHelperHttpGet(t *testing.T) {
+	url := os.Getenv("TLANG_HELPER_URL")
+	if url == "" {
+		return
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		os.Exit(1)
+	}
+	_, _ = io.Copy(os.Stdout, resp.Body)
+	os.Exit(0)
+}
+
+func TestTcpModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let c = tcp.connect(l.addr(), 1); let a = l.accept(); let v = [c.remoteAddr() == l.addr(), a.remoteAddr() == c.addr()];`, `[true, true]`},
+		{`let c = tcp.connect(l.addr()); let a = l.accept(); c.write("hello\nworld"); c.closeWrite(); let v = [a.readLine(), a.readBytes(3), a.read(), a.readLine()];`, `["hello", b"wor", "ld", void]`},
+		{`let c = tcp.connect(l.addr()); let a = l.accept(); a.write(bytes([1, 2])); a.close(); let v = [c.readBytes(10), c.readBytes(10), try(a.read)[1]];`, `[b"\x01\x02", b"", "native function socket.read: socket already closed"]`},
+		{`let c = tcp.connect(l.addr()); let a = l.accept(); a.write(bytes([255, 0, 1])); a.close(); let v = [c.readBytes(), c.readBytes()];`, `[b"\xff\x00\x01", b""]`},
+		{`let c = tcp.connect(l.addr()); let a = l.accept(); a.write("hi"); a.close(); let v = c.readBytes(9223372036854775807);`, `b"hi"`},
+		{`let c = tcp.connect(l.addr()); let a = l.accept(); a.setReadDeadline(0.01); let v = try(a.readLine)[1].endsWith("i/o timeout");`, `true`},
+		{`l.serve(func(conn) { conn.write("hi " + conn.readLine() + "\n"); }); let c = tcp.connect(l.addr()); c.write("m\n"); let d = tcp.connect(l.addr()); d.write("n\n"); let v = [d.readLine(), c.readLine(), c.readLine()];`, `["hi n", "hi m", void]`},
+		{`l.close(); let v = [try(l.accept)[1], try(tcp.connect, l.addr())[0]];`, `["native function socket.accept: socket already closed", void]`},
+		{`l.setAcceptDeadline(0.01); let v = try(l.accept)[1].endsWith("i/o timeout");`, `true`},
+		{`l.serve(func(conn) { conn.readLine(); error("boom"); }); let c = tcp.connect(l.addr()); c.write("x\n"); c.read(); l.close(); let v = [try(l.wait)[1], try(l.serve, func(conn) { 1; })[1]];`, `["boom", "native function socket.serve: socket already closed"]`},
+		{`l.serve(func(conn) { 1; }); let c = tcp.connect(l.addr()); c.read(); l.close(); let v = [l.wait(), try(l.accept)[1]];`, `[void, "native function socket.accept: socket already closed"]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(`let l = tcp.listen(); ` + tt.input + ` l.close(); v;`)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`let l = tcp.listen(); l.close(); l.close(); l.read();`, "native function socket.read: socket already closed"},
+		{`let l = tcp.listen(); let r = try(l.read); l.close(); error(r[1]);`, "native function socket.read: socket is a listener"},
+		{`let l = tcp.listen(); let c = tcp.connect(l.addr()); let r = try(c.accept); l.close(); error(r[1]);`, "native function socket.accept: socket is not a listener"},
+		{`tcp.listen(1);`, "native function tcp.listen: args[0] should be String"},
+		{`let l = tcp.listen(); let r = try(l.wait); l.close(); error(r[1]);`, "native function socket.wait: args[0] should be a served Socket"},
+		{`let l = tcp.listen(); l.serve(func(conn) { 1; }); let r = try(l.serve, func(conn) { 1; }); l.close(); error(r[1]);`, "native function socket.serve: socket already served"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+
+	defer func(s *Sandbox) { DefaultSandbox = s }(DefaultSandbox)
+	DefaultSandbox = NewSandbox()
+	DefaultSandbox.Drop(CapNet)
+	testErrObject(t, testEval(`tcp.listen();`), "native function tcp.listen: permission denied: net")
+}
+
+func TestModules(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	q := func(input string) string {
+		return strings.ReplaceAll(input, "DIR", strings.ReplaceAll(dir, "\\", "\\\\"))
+	}
+	files := map[string]string{
+		"lib/util.t":   `let count = 0; let _hidden = 1; let inc = func() { count += 1; count; }; let helper = import "./helper";`,
+		"lib/helper.t": `let name = "helper"; let loaded = fs.exists("DIR/lib/helper.t");`,
+		"old.t":        `let export = {"x": 1};`,
+		"path/p.t":     `let fromPath = 7;`,
+		"main.t":       `let util = import "lib/util.t"; let p = import "p";`,
+		"sub/a.t":      `import "./b.t";`,
+		"sub/b.t":      `import "../sub/a";`,
+		"bad.t":        `let x = ;`,
+		"fail.t":       `error("fail");`,
+		"num.t":        `let export = 1;`,
+		"lib/use.t":    `import { name } from "./helper"; import "helper" as h; let greeting = [name, h.loaded];`,
+		"lib/count.t":  `let n = 0; let inc = func() { n += 1; n; };`,
+		"path/pc.t":    `let n = 0; let inc = func() { n += 1; n; };`,
+		"cyc/a.t":      `import "../cyclink/b.t";`,
+		"cyc/b.t":      `import "./a.t";`,
+		"slow.t":       `fs.append("DIR/loads", "x"); time.sleep(0.3); let n = 1;`,
+		"dec.t":        `decimalContext(2, "down"); let third = func() { decimal(1) / 3; };`,
+	}
+	for name, content := range files {
+		_ = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(q(content)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"link": "lib", "cyclink": "cyc"} {
+		if err := os.Symlink(filepath.Join(dir, target), filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(paths []string) { ImportPaths = paths }(ImportPaths)
+	wd, _ := os.Getwd()
+	relPath, err := filepath.Rel(wd, filepath.Join(dir, "path"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ImportPaths = []string{relPath}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let u = import "DIR/lib/util.t"; [keys(u), u.helper];`, `[["count", "helper", "inc"], { "loaded": true, "name": "helper" }]`},
+		{`let u = import "DIR/lib/util.t"; let v = import "DIR/lib/util"; [u.inc(), v.inc(), u.count, import("DIR/lib/util").count];`, `[1, 2, 0, 2]`},
+		{`import("DIR/main.t").p;`, `{ "fromPath": 7 }`},
+		{`import "DIR/old.t";`, `{ "x": 1 }`},
+		{`import "p.t";`, `{ "fromPath": 7 }`},
+		{`import "DIR/lib/helper.t" as h; keys(h);`, `["loaded", "name"]`},
+		{`import { name, loaded as ok } from "DIR/lib/helper.t"; [name, ok];`, `["helper", true]`},
+		{`import { x } from "DIR/old.t"; x;`, `1`},
+		{`import { fromPath as f, } from "p"; f;`, `7`},
+		{`import("DIR/lib/use.t").greeting;`, `["helper", true]`},
+		{`let a = import "DIR/lib/count.t"; let b = import "DIR/link/count"; [a.inc(), b.inc()];`, `[1, 2]`},
+		{`let a = import "pc"; let b = import "DIR/path/pc.t"; [a.inc(), b.inc()];`, `[1, 2]`},
+		{`let d = import "DIR/dec.t"; [d.third(), decimal(1) / 3];`, `[0.33, 0.3333333333333333]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(q(tt.input))
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("input=%s, expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "DIR/sub/a.t";`, "native function import: circular import: DIR/sub/a.t -> DIR/sub/b.t -> DIR/sub/a.t"},
+		{`import "DIR/cyc/a.t";`, "native function import: circular import: DIR/cyc/a.t -> DIR/cyc/b.t -> DIR/cyc/a.t"},
+		{`import "DIR/none.t";`, "native function import: module DIR/none.t not found in DIR/none.t"},
+		{`import "DIR/bad.t";`, "native function import: parser errors in DIR/bad.t: no prefix parse function for ; found; expected next token to be ;, got Eof instead"},
+		{`import "DIR/fail.t";`, "fail"},
+		{`import "DIR/fail.t";`, "fail"},
+		{`import { nope } from "DIR/old.t";`, "import: DIR/old.t has no name nope"},
+		{`import { x } from "DIR/num.t";`, "import: DIR/num.t does not export a Hash"},
+		{`let x = 1; import { x } from "DIR/old.t";`, "identifier x already set"},
+		{`import "DIR/fail.t" as f;`, "fail"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(q(tt.input)), q(tt.expected))
+	}
+
+	// a handler importing a module while the script evaluates it waits for it
+	s, ok := testEval(q(`http.listen({"/": func(req) { string(import("DIR/slow.t").n); }});`)).(*Server)
+	if !ok {
+		t.Fatalf("http.listen should return a Server")
+	}
+	defer s.Server.Close()
+	body := make(chan string, 1)
+	go func() {
+		for {
+			if _, err := os.Stat(filepath.Join(dir, "loads")); err == nil {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		resp, err := http.Get("http://" + s.Listener.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+	testEval(q(`import "DIR/slow.t";`))
+	var got string
+	blocking(func() { got = <-body })
+	if got != "1" {
+		t.Errorf("handler should import the module, got=%s", got)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "loads")); string(data) != "x" {
+		t.Errorf("module should be evaluated once, got=%s", string(data))
+	}
+}
+
 func TestCharacterLiteral(t *testing.T) {
 	input := `'1';`
 
@@ -528,6 +1757,52 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3];", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2];", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:];", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:];", "[1, 2, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5][::2];", "[1, 3, 5]"},
+		{"[1, 2, 3, 4, 5][::-1];", "[5, 4, 3, 2, 1]"},
+		{"[1, 2, 3, 4, 5][-2:];", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-2];", "[1, 2, 3]"},
+		{"[1, 2, 3, 4, 5][-1:0:-2];", "[5, 3]"},
+		{"[1, 2, 3][5:9];", "[]"},
+		{"\"Hello\"[1:3];", "\"el\""},
+		{"\"Hello\"[::-1];", "\"olleH\""},
+		{"let a = [1, 2, 3, 4]; a[1:3] = ['x', 'y', 'z']; a;", "[1, 'x', 'y', 'z', 4]"},
+		{"let a = [1, 2, 3, 4]; a[1:3] = []; a;", "[1, 4]"},
+		{"let a = [1, 2, 3, 4]; a[:0] = [0]; a;", "[0, 1, 2, 3, 4]"},
+		{"let a = [1, 2, 3, 4]; a[::2] = [7, 8]; a;", "[7, 2, 8, 4]"},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 5; a;", "[1, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("expected=%s, got=%s", tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3][::0];", "slice: step should not be 0"},
+		{"[1, 2, 3][\"a\":];", "slice: index should be Integer"},
+		{"let a = [1, 2, 3, 4]; a[::2] = [1];", "slice assign: size of value 1 should be 2"},
+		{"let &a = [1, 2, 3]; &a[0:1] = [1];", "assign to const reference"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestArrayFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -596,6 +1871,131 @@ func TestReference(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2];", true},
+		{"[1, 2] == [1, 2, 3];", false},
+		{"[1, [2, \"a\"]] == [1, [2, \"a\"]];", true},
+		{"[1, [2, \"a\"]] != [1, [2, \"b\"]];", true},
+		{"[1, 2.0] == [1.0, 2];", true},
+		{"[void, true] == [void, true];", true},
+		{"[1, 2] < [1, 3];", true},
+		{"[1, 2] < [1, 2, 0];", true},
+		{"[2] > [1, 9];", true},
+		{"[1, 2] <= [1, 2];", true},
+		{"[\"b\"] >= [\"a\", \"z\"];", true},
+		{"[\"a\", \"z\"] >= [\"b\"];", false},
+		{"{\"a\": [1], 1: 2} == {1: 2, \"a\": [1]};", true},
+		{"{\"a\": 1} == {\"a\": 2};", false},
+		{"{\"a\": 1} != {\"a\": 1, \"b\": 2};", true},
+		{"let a = {}; a.a = a; let b = {}; b.a = b; a == b;", true},
+		{"{\"@==\": func(r) { ret true; }} == 3;", true},
+		{"tuple(1, 2) == tuple(1, 2);", true},
+		{"tuple(1, 2) < tuple(1, 3);", true},
+	}
+
+	for i, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected, i)
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = {1.5: 3}; a[1.5];", 3},
+		{"let a = {0.0: 3}; a[-0.0];", 3},
+		{"let a = {1: 3}; a[1.0];", 3},
+		{"let a = {2.0: 3}; a[2];", 3},
+		{"let a = {decimal(\"0.50\"): 3}; a[0.5];", 3},
+		{"len(keys({1: 1, 1.0: 2, decimal(\"1\"): 3}));", 1},
+		{"let a = {void: 4}; a[void];", 4},
+		{"let a = {tuple(1, \"x\"): 5}; a[tuple(1, \"x\")];", 5},
+		{"let a = {tuple(1, tuple(2, 3)): 6}; a[tuple(1, tuple(2, 3))];", 6},
+		{"let a = {}; a[tuple(1, 2)] = 7; a[tuple(1, 2)];", 7},
+		{"let a = {tuple(1): 1, tuple(\"1\"): 2}; a[tuple(\"1\")];", 2},
+		{"len(tuple(1, 2, 3));", 3},
+		{"tuple(1, 2, 3)[1];", 2},
+		{"tuple(1, 2, 3)[1:][0];", 2},
+		{"let s = 0; loop x in (tuple(1, 2, 3)) { s += x; }; s;", 6},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = {}; a[[1, 2]] = 1;", "unusable as hash key: Array"},
+		{"tuple([1]);", "native function tuple: unusable as tuple element: Array"},
+		{"let t = tuple(1, 2); t[0] = 3;", "assign to const reference"},
+		{"[1] == tuple(1);", "type mismatch: Array == Tuple"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"set(1, 2, 2, 3);", "set(1, 2, 3)"},
+		{"set();", "set()"},
+		{"set(3, \"a\", tuple(1, 2));", "set(3, \"a\", (1, 2))"},
+		{"set(1, 2) + set(2, 3);", "set(1, 2, 3)"},
+		{"set(1, 2, 3) - set(2);", "set(1, 3)"},
+		{"union(set(1), set(2), set(1, 3));", "set(1, 2, 3)"},
+		{"intersect(set(1, 2, 3), set(3, 2, 5));", "set(2, 3)"},
+		{"difference(set(1, 2, 3), set(1), set(3));", "set(2)"},
+		{"call(set, [3, 1, 3]);", "set(3, 1)"},
+		{"len(set(1, 1, 1, 2));", "2"},
+		{"let s = 0; loop x in (set(1, 2, 2, 3)) { s += x; }; s;", "6"},
+		{"2 in set(1, 2);", "true"},
+		{"4 in set(1, 2);", "false"},
+		{"[1] in set(1, 2);", "false"},
+		{"set(1, 2) == set(2, 1);", "true"},
+		{"set(1, 2) != set(1);", "true"},
+		{"set(1) <= set(1, 2);", "true"},
+		{"set(1) < set(1);", "false"},
+		{"set(1, 2) > set(2);", "true"},
+		{"{set(1, 2): 3}[set(2, 1)];", "3"},
+		{"2 in [1, 2];", "true"},
+		{"\"a\" in {\"a\": 1};", "true"},
+		{"\"ell\" in \"Hello\";", "true"},
+		{"'z' in \"Hello\";", "false"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect(16, nil) != tt.expected {
+			t.Errorf("expected=%s, got=%s", tt.expected, evaluated.Inspect(16, nil))
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"set([1]);", "native function set: unusable as set element: Array"},
+		{"union(set(1), [2]);", "native function union: args[1] should be Set"},
+		{"set(1) + [2];", "type mismatch: Set + Array"},
+		{"1 in 2;", "unknown operator: Integer in Integer"},
+	}
+
+	for _, tt := range errTests {
+		testErrObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
     {
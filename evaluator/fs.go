@@ -0,0 +1,420 @@
+package evaluator
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// pathArg checks the capability c on args[i] with DefaultSandbox.
+func pathArg(name string, c Capability, args []Object, i int) (string, Object) {
+	path, err := stringArg(name, args, i)
+	if err != nil {
+		return "", err
+	}
+	checked, e := DefaultSandbox.CheckPath(name, c, string(path))
+	if e != nil {
+		return "", e
+	}
+	return checked, nil
+}
+
+func dataArg(name string, args []Object, i int) ([]byte, Object) {
+	if i < len(args) {
+		switch data := UnwrapReferenceValue(args[i]).(type) {
+		case *String:
+			return []byte(string(data.Value)), nil
+		case *Bytes:
+			return data.Value, nil
+		}
+	}
+	return nil, newError("native function %s: args[%d] should be String or Bytes", name, i)
+}
+
+func fsError(name string, err error) Object {
+	return newError("native function %s: %s", name, err.Error())
+}
+
+func newFsNative(name string, min, max int, fn func(args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function fs.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function fs.%s: len(args) should be %d to %d", name, min, max)
+		}
+		return fn(args)
+	}}
+}
+
+func writeFile(name string, args []Object, flag int) Object {
+	path, err := pathArg(name, CapWrite, args, 0)
+	if err != nil {
+		return err
+	}
+	data, err := dataArg(name, args, 1)
+	if err != nil {
+		return err
+	}
+	var e error
+	blocking(func() {
+		var f *os.File
+		if f, e = os.OpenFile(path, flag, 0644); e != nil {
+			return
+		}
+		_, e = f.Write(data)
+		if closeErr := f.Close(); e == nil {
+			e = closeErr
+		}
+	})
+	if e != nil {
+		return fsError(name, e)
+	}
+	return VoidObj
+}
+
+func newFileNative(name string, min, max int, fn func(f *File, args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function file.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function file.%s: len(args) should be %d to %d", name, min, max)
+		}
+		f, ok := UnwrapReferenceValue(args[0]).(*File)
+		if !ok {
+			return newError("native function file.%s: args[0] should be File", name)
+		}
+		if f.File == nil {
+			return newError("native function file.%s: file already closed", name)
+		}
+		return fn(f, args)
+	}}
+}
+
+func readerOf(name string, f *File) (*bufio.Reader, Object) {
+	if f.Reader == nil {
+		return nil, newError("native function file.%s: file not opened for reading", name)
+	}
+	return f.Reader, nil
+}
+
+// readLine reads a line without its "\n" or "\r\n", and gives void at the end.
+func readLine(r *bufio.Reader) (Object, error) {
+	line, e := r.ReadString('\n')
+	if e == io.EOF && line == "" {
+		return VoidObj, nil
+	}
+	if e != nil && e != io.EOF {
+		return nil, e
+	}
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		line = line[:len(line)-1]
+		if len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+	}
+	return &String{Value: []rune(line)}, nil
+}
+
+// fileMethods are the methods of File, the file is args[0].
+var fileMethods = map[string]*Native{
+	"read": newFileNative("read", 1, 1, func(f *File, args []Object) Object {
+		r, err := readerOf("read", f)
+		if err != nil {
+			return err
+		}
+		var data []byte
+		var e error
+		blocking(func() { data, e = ioutil.ReadAll(r) })
+		if e != nil {
+			return fsError("file.read", e)
+		}
+		return &String{Value: []rune(string(data))}
+	}),
+	"readBytes": newFileNative("readBytes", 1, 2, func(f *File, args []Object) Object {
+		r, err := readerOf("readBytes", f)
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			var data []byte
+			var e error
+			blocking(func() { data, e = ioutil.ReadAll(r) })
+			if e != nil {
+				return fsError("file.readBytes", e)
+			}
+			return &Bytes{Value: data}
+		}
+		n, err := integerArg("file.readBytes", args, 1)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return newError("native function file.readBytes: args[1] should be non-negative")
+		}
+		if n > maxBytesLen {
+			return newError("native function file.readBytes: args[1] should be at most %d", maxBytesLen)
+		}
+		// the buffer grows with what is read, not with n
+		var data bytes.Buffer
+		var e error
+		blocking(func() { _, e = io.CopyN(&data, r, n) })
+		if e != nil && e != io.EOF {
+			return fsError("file.readBytes", e)
+		}
+		return &Bytes{Value: data.Bytes()}
+	}),
+	"readLine": newFileNative("readLine", 1, 1, func(f *File, args []Object) Object {
+		r, err := readerOf("readLine", f)
+		if err != nil {
+			return err
+		}
+		var line Object
+		var e error
+		blocking(func() { line, e = readLine(r) })
+		if e != nil {
+			return fsError("file.readLine", e)
+		}
+		return line
+	}),
+	"write": newFileNative("write", 2, 2, func(f *File, args []Object) Object {
+		if f.Mode == "r" {
+			return newError("native function file.write: file not opened for writing")
+		}
+		data, err := dataArg("file.write", args, 1)
+		if err != nil {
+			return err
+		}
+		file := f.File
+		var e error
+		blocking(func() { _, e = file.Write(data) })
+		if e != nil {
+			return fsError("file.write", e)
+		}
+		return VoidObj
+	}),
+	"close": newFileNative("close", 1, 1, func(f *File, args []Object) Object {
+		e := f.File.Close()
+		f.File, f.Reader = nil, nil
+		if e != nil {
+			return fsError("file.close", e)
+		}
+		return VoidObj
+	}),
+}
+
+func newFsModule() *Hash {
+	return newModule(map[string]Object{
+		"read": newFsNative("read", 1, 1, func(args []Object) Object {
+			path, err := pathArg("fs.read", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			var data []byte
+			var e error
+			blocking(func() { data, e = ioutil.ReadFile(path) })
+			if e != nil {
+				return fsError("fs.read", e)
+			}
+			return &String{Value: []rune(string(data))}
+		}),
+		"readBytes": newFsNative("readBytes", 1, 1, func(args []Object) Object {
+			path, err := pathArg("fs.readBytes", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			var data []byte
+			var e error
+			blocking(func() { data, e = ioutil.ReadFile(path) })
+			if e != nil {
+				return fsError("fs.readBytes", e)
+			}
+			return &Bytes{Value: data}
+		}),
+		"write": newFsNative("write", 2, 2, func(args []Object) Object {
+			return writeFile("fs.write", args, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		}),
+		"append": newFsNative("append", 2, 2, func(args []Object) Object {
+			return writeFile("fs.append", args, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+		}),
+		"open": newFsNative("open", 1, 2, func(args []Object) Object {
+			mode := "r"
+			if len(args) == 2 {
+				m, err := stringArg("fs.open", args, 1)
+				if err != nil {
+					return err
+				}
+				mode = string(m)
+			}
+			var c Capability
+			var flag int
+			switch mode {
+			case "r":
+				c, flag = CapRead, os.O_RDONLY
+			case "w":
+				c, flag = CapWrite, os.O_WRONLY|os.O_CREATE|os.O_TRUNC
+			case "a":
+				c, flag = CapWrite, os.O_WRONLY|os.O_CREATE|os.O_APPEND
+			default:
+				return newError("native function fs.open: unknown mode %s", mode)
+			}
+			name, err := stringArg("fs.open", args, 0)
+			if err != nil {
+				return err
+			}
+			path, err := pathArg("fs.open", c, args, 0)
+			if err != nil {
+				return err
+			}
+			var f *os.File
+			var e error
+			blocking(func() { f, e = os.OpenFile(path, flag, 0644) })
+			if e != nil {
+				return fsError("fs.open", e)
+			}
+			file := &File{File: f, Path: string(name), Mode: mode}
+			if mode == "r" {
+				file.Reader = bufio.NewReader(f)
+			}
+			return file
+		}),
+		"list": newFsNative("list", 1, 1, func(args []Object) Object {
+			path, err := pathArg("fs.list", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			var infos []os.FileInfo
+			var e error
+			blocking(func() { infos, e = ioutil.ReadDir(path) })
+			if e != nil {
+				return fsError("fs.list", e)
+			}
+			names := make([]string, len(infos))
+			for i, info := range infos {
+				names[i] = info.Name()
+			}
+			sort.Strings(names)
+			return stringsToArray(names)
+		}),
+		"stat": newFsNative("stat", 1, 1, func(args []Object) Object {
+			path, err := pathArg("fs.stat", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			var info os.FileInfo
+			var e error
+			blocking(func() { info, e = os.Stat(path) })
+			if e != nil {
+				return fsError("fs.stat", e)
+			}
+			return newModule(map[string]Object{
+				"name":     &String{Value: []rune(info.Name())},
+				"size":     &Integer{Value: info.Size()},
+				"isDir":    nativeBoolToBooleanObject(info.IsDir()),
+				"mode":     &Integer{Value: int64(info.Mode().Perm())},
+				"modified": &Time{Value: info.ModTime()},
+			})
+		}),
+		"exists": newFsNative("exists", 1, 1, func(args []Object) Object {
+			path, err := pathArg("fs.exists", CapRead, args, 0)
+			if err != nil {
+				return err
+			}
+			var e error
+			blocking(func() { _, e = os.Stat(path) })
+			return nativeBoolToBooleanObject(e == nil)
+		}),
+		"mkdir": newFsNative("mkdir", 1, 2, func(args []Object) Object {
+			path, err := pathArg("fs.mkdir", CapWrite, args, 0)
+			if err != nil {
+				return err
+			}
+			mkdir := os.Mkdir
+			if len(args) == 2 && isTruthy(UnwrapReferenceValue(args[1])) {
+				mkdir = os.MkdirAll
+			}
+			var e error
+			blocking(func() { e = mkdir(path, 0755) })
+			if e != nil {
+				return fsError("fs.mkdir", e)
+			}
+			return VoidObj
+		}),
+		"remove": newFsNative("remove", 1, 2, func(args []Object) Object {
+			path, err := pathArg("fs.remove", CapWrite, args, 0)
+			if err != nil {
+				return err
+			}
+			all := len(args) == 2 && isTruthy(UnwrapReferenceValue(args[1]))
+			var e error
+			blocking(func() {
+				if !all {
+					e = os.Remove(path)
+				} else if _, e = os.Lstat(path); e == nil {
+					e = os.RemoveAll(path)
+				}
+			})
+			if e != nil {
+				return fsError("fs.remove", e)
+			}
+			return VoidObj
+		}),
+		"rename": newFsNative("rename", 2, 2, func(args []Object) Object {
+			from, err := pathArg("fs.rename", CapWrite, args, 0)
+			if err != nil {
+				return err
+			}
+			to, err := pathArg("fs.rename", CapWrite, args, 1)
+			if err != nil {
+				return err
+			}
+			var e error
+			blocking(func() { e = os.Rename(from, to) })
+			if e != nil {
+				return fsError("fs.rename", e)
+			}
+			return VoidObj
+		}),
+		"join": &Native{Fn: func(env *Environment, args []Object) Object {
+			elems := make([]string, len(args))
+			for i := range args {
+				elem, err := stringArg("fs.join", args, i)
+				if err != nil {
+					return err
+				}
+				elems[i] = string(elem)
+			}
+			return &String{Value: []rune(filepath.Join(elems...))}
+		}},
+		"clean": newPathNative("clean", filepath.Clean),
+		"base":  newPathNative("base", filepath.Base),
+		"dir":   newPathNative("dir", filepath.Dir),
+		"ext":   newPathNative("ext", filepath.Ext),
+		"abs": newFsNative("abs", 1, 1, func(args []Object) Object {
+			path, err := stringArg("fs.abs", args, 0)
+			if err != nil {
+				return err
+			}
+			abs, e := filepath.Abs(string(path))
+			if e != nil {
+				return fsError("fs.abs", e)
+			}
+			return &String{Value: []rune(abs)}
+		}),
+	})
+}
+
+func newPathNative(name string, fn func(path string) string) *Native {
+	return newFsNative(name, 1, 1, func(args []Object) Object {
+		path, err := stringArg("fs."+name, args, 0)
+		if err != nil {
+			return err
+		}
+		return &String{Value: []rune(fn(string(path)))}
+	})
+}
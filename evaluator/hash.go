@@ -0,0 +1,188 @@
+package evaluator
+
+import (
+	"sort"
+	"strings"
+)
+
+func hashArg(name string, args []Object, i int) (*Hash, Object) {
+	if i < len(args) {
+		if hash, ok := UnwrapReferenceValue(args[i]).(*Hash); ok {
+			return hash, nil
+		}
+	}
+	return nil, newError("native function %s: args[%d] should be Hash", name, i)
+}
+
+func hashKeyArg(name string, args []Object, i int) (HashAble, Object) {
+	if i < len(args) {
+		if key, ok := UnwrapReferenceValue(args[i]).(HashAble); ok {
+			return key, nil
+		}
+		return nil, newError("native function %s: unusable as hash key: %s", name, UnwrapReferenceValue(args[i]).Type())
+	}
+	return nil, newError("native function %s: len(args) should be %d", name, i+1)
+}
+
+func newHashNative(name string, min, max int, fn func(hash *Hash, args []Object, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			if max < 0 {
+				return newError("native function %s: len(args) should be at least %d", name, min)
+			}
+			if min == max {
+				return newError("native function %s: len(args) should be %d", name, min)
+			}
+			return newError("native function %s: len(args) should be %d to %d", name, min, max)
+		}
+		hash, err := hashArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return fn(hash, args, env)
+	}}
+}
+
+// ownPairs returns the pairs of hash in order, leaving out the keys starting
+// with "@", which belong to the class protocol.
+func ownPairs(hash *Hash) []HashPair {
+	var pairs []HashPair
+	for _, key := range hash.Ordered() {
+		if s, ok := key.Value.(string); ok && key.Type == STRING && strings.HasPrefix(s, "@") {
+			continue
+		}
+		pairs = append(pairs, hash.Pairs[key])
+	}
+	return pairs
+}
+
+func pairValue(pair HashPair) Object {
+	if *pair.Value == nil {
+		return VoidObj
+	}
+	return UnwrapReferenceValue(*pair.Value).Copy()
+}
+
+// hashNatives are registered in SharedEnv, and can be called as methods of
+// Hash when the hash has no such key, e.g. h.keys() is keys(h).
+var hashNatives map[string]*Native
+
+func newHashNatives() map[string]*Native {
+	return map[string]*Native{
+		"keys": newHashNative("keys", 1, 1, func(hash *Hash, args []Object, env *Environment) Object {
+			elements := []Object{}
+			for _, pair := range ownPairs(hash) {
+				elements = append(elements, pair.Key)
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"values": newHashNative("values", 1, 1, func(hash *Hash, args []Object, env *Environment) Object {
+			elements := []Object{}
+			for _, pair := range ownPairs(hash) {
+				elements = append(elements, pairValue(pair))
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"entries": newHashNative("entries", 1, 1, func(hash *Hash, args []Object, env *Environment) Object {
+			elements := []Object{}
+			for _, pair := range ownPairs(hash) {
+				elements = append(elements, &Array{Elements: []Object{pair.Key, pairValue(pair)}, Xvalue: true})
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+		"has": newHashNative("has", 2, 2, func(hash *Hash, args []Object, env *Environment) Object {
+			key, err := hashKeyArg("has", args, 1)
+			if err != nil {
+				return err
+			}
+			for {
+				if pair, ok := hash.Pairs[key.HashKey()]; ok && *pair.Value != nil {
+					return TrueObj
+				}
+				if hash, _ = template(hash); hash == nil {
+					return FalseObj
+				}
+			}
+		}),
+		"hasOwn": newHashNative("hasOwn", 2, 2, func(hash *Hash, args []Object, env *Environment) Object {
+			key, err := hashKeyArg("hasOwn", args, 1)
+			if err != nil {
+				return err
+			}
+			pair, ok := hash.Pairs[key.HashKey()]
+			return nativeBoolToBooleanObject(ok && *pair.Value != nil)
+		}),
+		"remove": newHashNative("remove", 2, 2, func(hash *Hash, args []Object, env *Environment) Object {
+			if refer, ok := args[0].(*Reference); !ok || refer.Const {
+				return newError("native function remove: assign to const reference")
+			}
+			key, err := hashKeyArg("remove", args, 1)
+			if err != nil {
+				return err
+			}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return VoidObj
+			}
+			removed := pairValue(pair)
+			hash.Free(key)
+			return removed
+		}),
+		"merge": newHashNative("merge", 1, -1, func(hash *Hash, args []Object, env *Environment) Object {
+			merged := cloneHash(hash)
+			for i := range args[1:] {
+				other, err := hashArg("merge", args, i+1)
+				if err != nil {
+					return err
+				}
+				cloned := cloneHash(other)
+				for _, key := range cloned.Ordered() {
+					merged.Set(key, cloned.Pairs[key])
+				}
+			}
+			merged.Xvalue = true
+			return merged
+		}),
+		"clone": newHashNative("clone", 1, 1, func(hash *Hash, args []Object, env *Environment) Object {
+			cloned := cloneHash(hash)
+			cloned.Xvalue = true
+			return cloned
+		}),
+	}
+}
+
+// cloneHash copies hash and its values, while templates are shared.
+func cloneHash(hash *Hash) *Hash {
+	cloned := &Hash{Pairs: make(map[HashKey]HashPair, len(hash.Pairs))}
+	for _, key := range hash.Ordered() {
+		pair := hash.Pairs[key]
+		var value Object
+		if *pair.Value != nil {
+			value = (*pair.Value).Copy()
+			if key == (HashKey{Type: STRING, Value: "@template"}) {
+				value = *pair.Value
+			}
+		}
+		cloned.Set(key, HashPair{Key: pair.Key, Value: &value})
+	}
+	return cloned
+}
+
+// newModule makes a Hash holding the members of a native module, e.g. math.
+func newModule(members map[string]Object) *Hash {
+	module := &Hash{Pairs: make(map[HashKey]HashPair, len(members))}
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		setMember(module, name, members[name])
+	}
+	return module
+}
+
+func setMember(hash *Hash, name string, member Object) {
+	key := &String{Value: []rune(name)}
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: &member})
+}
@@ -0,0 +1,402 @@
+package evaluator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// httpRoute is a route of http.listen, "GET /a" only matches GET requests
+// for /a, "/a" matches any method, and a path ending with "/" matches every
+// path under it.
+type httpRoute struct {
+	method  string
+	path    string
+	handler Functor
+}
+
+func httpRoutes(routes *Hash) ([]httpRoute, Object) {
+	var parsed []httpRoute
+	for _, pair := range ownPairs(routes) {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return nil, newError("native function http.listen: route should be String: %s", pair.Key.Inspect(16, nil))
+		}
+		route := httpRoute{path: string(key.Value)}
+		if i := strings.Index(route.path, " "); i >= 0 {
+			route.method, route.path = route.path[:i], strings.TrimSpace(route.path[i+1:])
+		}
+		if !strings.HasPrefix(route.path, "/") {
+			return nil, newError("native function http.listen: route should be like \"GET /path\": %s", string(key.Value))
+		}
+		route.handler, ok = UnwrapReferenceValue(pairValue(pair)).(Functor)
+		if !ok {
+			return nil, newError("native function http.listen: handler of %s should be Functor", string(key.Value))
+		}
+		parsed = append(parsed, route)
+	}
+	return parsed, nil
+}
+
+// matchRoute returns the route with the longest path matching r, and the
+// status to answer with when there is none.
+func matchRoute(routes []httpRoute, r *http.Request) (*httpRoute, int) {
+	var matched *httpRoute
+	status := http.StatusNotFound
+	for i, route := range routes {
+		if route.path != r.URL.Path && !(strings.HasSuffix(route.path, "/") && strings.HasPrefix(r.URL.Path, route.path)) {
+			continue
+		}
+		if route.method != "" && route.method != r.Method {
+			status = http.StatusMethodNotAllowed
+			continue
+		}
+		if matched == nil || len(route.path) > len(matched.path) {
+			matched = &routes[i]
+		}
+	}
+	return matched, status
+}
+
+func headersHash(header http.Header) *Hash {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := &Hash{Pairs: make(map[HashKey]HashPair, len(names)), Xvalue: true}
+	for _, name := range names {
+		setMember(hash, name, &String{Value: []rune(strings.Join(header[name], ", "))})
+	}
+	return hash
+}
+
+func setHeaders(name string, header http.Header, value Object) Object {
+	headers, ok := value.(*Hash)
+	if !ok {
+		return newError("native function %s: headers should be Hash", name)
+	}
+	for _, pair := range ownPairs(headers) {
+		key, ok := pair.Key.(*String)
+		val, err := stringArg(name, []Object{UnwrapReferenceValue(pairValue(pair))}, 0)
+		if !ok || err != nil {
+			return newError("native function %s: headers should be Hash of String", name)
+		}
+		header.Set(string(key.Value), string(val))
+	}
+	return nil
+}
+
+func requestHash(r *http.Request, body []byte) *Hash {
+	query := &Hash{Pairs: make(map[HashKey]HashPair), Xvalue: true}
+	values := r.URL.Query()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		setMember(query, name, &String{Value: []rune(values.Get(name))})
+	}
+	req := &Hash{Pairs: make(map[HashKey]HashPair), Xvalue: true}
+	setMember(req, "method", &String{Value: []rune(r.Method)})
+	setMember(req, "path", &String{Value: []rune(r.URL.Path)})
+	setMember(req, "query", query)
+	setMember(req, "headers", headersHash(r.Header))
+	setMember(req, "body", &String{Value: []rune(string(body))})
+	setMember(req, "bodyBytes", &Bytes{Value: body})
+	setMember(req, "remote", &String{Value: []rune(r.RemoteAddr)})
+	return req
+}
+
+// httpResponse is what a handler answers, a String or Bytes is the body, a
+// Hash has "status", "headers" and "body", and void is 204 No Content.
+type httpResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func responseOf(result Object) httpResponse {
+	response := httpResponse{status: http.StatusOK, header: http.Header{}}
+	fail := func(err Object) httpResponse {
+		return httpResponse{
+			status: http.StatusInternalServerError,
+			header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			body:   []byte(err.(*Err).Message),
+		}
+	}
+	switch result := result.(type) {
+	case *Err:
+		return fail(result)
+	case *Void:
+		response.status = http.StatusNoContent
+	case *String, *Bytes:
+		response.body, _ = dataArg("http", []Object{result}, 0)
+	case *Hash:
+		for _, pair := range ownPairs(result) {
+			value := UnwrapReferenceValue(pairValue(pair))
+			switch key := pair.Key.(type) {
+			case *String:
+				switch string(key.Value) {
+				case "status":
+					status, ok := value.(*Integer)
+					if !ok || status.Big != nil || status.Value < 100 || status.Value > 999 {
+						return fail(newError("native function http: status should be Integer from 100 to 999"))
+					}
+					response.status = int(status.Value)
+				case "headers":
+					if err := setHeaders("http", response.header, value); err != nil {
+						return fail(err)
+					}
+				case "body":
+					body, err := dataArg("http: body", []Object{value}, 0)
+					if err != nil {
+						return fail(newError("native function http: body should be String or Bytes"))
+					}
+					response.body = body
+				default:
+					return fail(newError("native function http: unknown response key %s", string(key.Value)))
+				}
+			default:
+				return fail(newError("native function http: unknown response key %s", key.Inspect(16, nil)))
+			}
+		}
+	default:
+		return fail(newError("native function http: response should be String, Bytes, Hash or void, got %s", result.Type()))
+	}
+	return response
+}
+
+// httpHandler runs the handlers in the interpreter, one at a time, while the
+// reading and writing of requests and responses happen concurrently.
+type httpHandler struct {
+	routes []httpRoute
+	env    *Environment
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, status := matchRoute(h.routes, r)
+	if route == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	body, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		http.Error(w, e.Error(), http.StatusBadRequest)
+		return
+	}
+	var response httpResponse
+	inInterpreter(func() {
+		response = responseOf(callFunctor(route.handler, []Object{requestHash(r, body)}, h.env))
+	})
+	for name, values := range response.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(response.status)
+	_, _ = w.Write(response.body)
+}
+
+func newServerNative(name string, fn func(s *Server) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function server.%s: len(args) should be 1", name)
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Server)
+		if !ok {
+			return newError("native function server.%s: args[0] should be Server", name)
+		}
+		return fn(s)
+	}}
+}
+
+// serverMethods are the methods of Server, which is args[0].
+var serverMethods = map[string]*Native{
+	"addr": newServerNative("addr", func(s *Server) Object {
+		return &String{Value: []rune(s.Listener.Addr().String())}
+	}),
+	"url": newServerNative("url", func(s *Server) Object {
+		return &String{Value: []rune("http://" + s.Listener.Addr().String())}
+	}),
+	// close stops the server, handlers waiting for the interpreter still run
+	"close": newServerNative("close", func(s *Server) Object {
+		if e := s.Server.Close(); e != nil {
+			return newError("native function server.close: %s", e.Error())
+		}
+		return VoidObj
+	}),
+	// wait serves until the server is closed, by a handler or another script
+	"wait": newServerNative("wait", func(s *Server) Object {
+		blocking(func() { <-s.Done })
+		return VoidObj
+	}),
+}
+
+// httpRequest sends a request with the options "headers", "body" and
+// "timeout" in seconds, and gets {"status", "headers", "body"}.
+func httpRequest(name string, method string, url string, body []byte, options *Hash) Object {
+	if err := DefaultSandbox.Check(name, CapNet); err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	header := http.Header{}
+	if options != nil {
+		for _, pair := range ownPairs(options) {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return newError("native function %s: unknown option %s", name, pair.Key.Inspect(16, nil))
+			}
+			value := UnwrapReferenceValue(pairValue(pair))
+			switch string(key.Value) {
+			case "headers":
+				if err := setHeaders(name, header, value); err != nil {
+					return err
+				}
+			case "body":
+				data, err := dataArg(name, []Object{value}, 0)
+				if err != nil {
+					return newError("native function %s: body should be String or Bytes", name)
+				}
+				body = data
+			case "timeout":
+				seconds, err := floatArg(name, []Object{value}, 0)
+				if err != nil {
+					return newError("native function %s: timeout should be Integer or Float", name)
+				}
+				client.Timeout = secondsToDuration(seconds)
+			default:
+				return newError("native function %s: unknown option %s", name, string(key.Value))
+			}
+		}
+	}
+	req, e := http.NewRequest(method, url, bytes.NewReader(body))
+	if e != nil {
+		return newError("native function %s: %s", name, e.Error())
+	}
+	req.Header = header
+
+	var resp *http.Response
+	var data []byte
+	blocking(func() {
+		resp, e = client.Do(req)
+		if e == nil {
+			data, e = ioutil.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+		}
+	})
+	if e != nil {
+		return newError("native function %s: %s", name, e.Error())
+	}
+	result := &Hash{Pairs: make(map[HashKey]HashPair), Xvalue: true}
+	setMember(result, "status", &Integer{Value: int64(resp.StatusCode)})
+	setMember(result, "headers", headersHash(resp.Header))
+	setMember(result, "body", &String{Value: []rune(string(data))})
+	setMember(result, "bodyBytes", &Bytes{Value: data})
+	return result
+}
+
+func optionsArg(name string, args []Object, i int) (*Hash, Object) {
+	if i >= len(args) {
+		return nil, nil
+	}
+	return hashArg(name, args, i)
+}
+
+func newHttpModule() *Hash {
+	return newModule(map[string]Object{
+		// http.listen(addr, routes) serves routes at addr, 127.0.0.1 with a
+		// free port when addr is omitted
+		"listen": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function http.listen: len(args) should be 1 to 2")
+			}
+			if err := DefaultSandbox.Check("http.listen", CapNet); err != nil {
+				return err
+			}
+			addr := "127.0.0.1:0"
+			if len(args) == 2 {
+				str, err := stringArg("http.listen", args, 0)
+				if err != nil {
+					return err
+				}
+				addr, args = string(str), args[1:]
+			}
+			routes, err := hashArg("http.listen", args, 0)
+			if err != nil {
+				return newError("native function http.listen: routes should be Hash")
+			}
+			parsed, err := httpRoutes(routes)
+			if err != nil {
+				return err
+			}
+			listener, e := net.Listen("tcp", addr)
+			if e != nil {
+				return newError("native function http.listen: %s", e.Error())
+			}
+			s := &Server{
+				Server:   &http.Server{Handler: &httpHandler{routes: parsed, env: env}},
+				Listener: listener,
+				Done:     make(chan struct{}),
+			}
+			go func() {
+				_ = s.Server.Serve(listener)
+				close(s.Done)
+			}()
+			return s
+		}},
+		"get": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function http.get: len(args) should be 1 to 2")
+			}
+			url, err := stringArg("http.get", args, 0)
+			if err != nil {
+				return err
+			}
+			options, err := optionsArg("http.get", args, 1)
+			if err != nil {
+				return err
+			}
+			return httpRequest("http.get", http.MethodGet, string(url), nil, options)
+		}},
+		"post": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("native function http.post: len(args) should be 2 to 3")
+			}
+			url, err := stringArg("http.post", args, 0)
+			if err != nil {
+				return err
+			}
+			body, err := dataArg("http.post", args, 1)
+			if err != nil {
+				return err
+			}
+			options, err := optionsArg("http.post", args, 2)
+			if err != nil {
+				return err
+			}
+			return httpRequest("http.post", http.MethodPost, string(url), body, options)
+		}},
+		"request": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("native function http.request: len(args) should be 2 to 3")
+			}
+			method, err := stringArg("http.request", args, 0)
+			if err != nil {
+				return err
+			}
+			url, err := stringArg("http.request", args, 1)
+			if err != nil {
+				return err
+			}
+			options, err := optionsArg("http.request", args, 2)
+			if err != nil {
+				return err
+			}
+			return httpRequest("http.request", strings.ToUpper(string(method)), string(url), nil, options)
+		}},
+	})
+}
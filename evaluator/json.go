@@ -0,0 +1,480 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsonEncoder writes values as JSON, visiting holds the Arrays and Hashes on
+// the way from the top value, so that a cycle is reported instead of looping.
+type jsonEncoder struct {
+	buf      bytes.Buffer
+	indent   string
+	visiting map[Object]bool
+	env      *Environment
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	if e.indent != "" {
+		e.buf.WriteByte('\n')
+		e.buf.WriteString(strings.Repeat(e.indent, depth))
+	}
+}
+
+func (e *jsonEncoder) enter(obj Object, path string) string {
+	if e.visiting[obj] {
+		return "cycle at " + path
+	}
+	e.visiting[obj] = true
+	return ""
+}
+
+func (e *jsonEncoder) encode(obj Object, path string, depth int) string {
+	switch obj := UnwrapReferenceValue(obj).(type) {
+	case *Void:
+		e.buf.WriteString("null")
+	case *Boolean:
+		e.buf.WriteString(strconv.FormatBool(obj.Value))
+	case *Integer:
+		e.buf.WriteString(obj.Inspect(0, e.env))
+	case *Decimal:
+		e.buf.WriteString(obj.Inspect(0, e.env))
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return fmt.Sprintf("unsupported value %g at %s", obj.Value, path)
+		}
+		str := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+		if !strings.ContainsAny(str, ".e") {
+			str += ".0"
+		}
+		e.buf.WriteString(str)
+	case *String:
+		writeJsonString(&e.buf, string(obj.Value))
+	case *Character:
+		writeJsonString(&e.buf, string(obj.Value))
+	case *Array:
+		return e.encodeElements(obj, obj.Elements, path, depth)
+	case *Tuple:
+		return e.encodeElements(obj, obj.Elements, path, depth)
+	case *Set:
+		return e.encodeElements(obj, obj.Elements, path, depth)
+	case *Hash:
+		return e.encodeHash(obj, path, depth)
+	default:
+		return fmt.Sprintf("unsupported type %s at %s", obj.Type(), path)
+	}
+	return ""
+}
+
+func (e *jsonEncoder) encodeElements(obj Object, elements []Object, path string, depth int) string {
+	if msg := e.enter(obj, path); msg != "" {
+		return msg
+	}
+	defer delete(e.visiting, obj)
+	if len(elements) == 0 {
+		e.buf.WriteString("[]")
+		return ""
+	}
+	e.buf.WriteByte('[')
+	for i, element := range elements {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		if msg := e.encode(element, fmt.Sprintf("%s[%d]", path, i), depth+1); msg != "" {
+			return msg
+		}
+	}
+	e.newline(depth)
+	e.buf.WriteByte(']')
+	return ""
+}
+
+func (e *jsonEncoder) encodeHash(hash *Hash, path string, depth int) string {
+	if msg := e.enter(hash, path); msg != "" {
+		return msg
+	}
+	defer delete(e.visiting, hash)
+
+	// a Hash with @json is written as what @json returns
+	ref := applyIndex(hash, []Object{&String{Value: []rune("@json")}}, Default, e.env).(*Reference)
+	if ref.Value != nil {
+		value := UnwrapReferenceValue(applyCall(ref, []Object{}, e.env))
+		if err, ok := value.(*Err); ok {
+			return err.Message
+		}
+		return e.encode(value, path, depth)
+	}
+
+	pairs := ownPairs(hash)
+	if len(pairs) == 0 {
+		e.buf.WriteString("{}")
+		return ""
+	}
+	e.buf.WriteByte('{')
+	for i, pair := range pairs {
+		var key string
+		switch k := pair.Key.(type) {
+		case *String:
+			key = string(k.Value)
+		case *Character:
+			key = string(k.Value)
+		case *Integer, *Float, *Decimal, *Boolean:
+			key = k.Inspect(0, e.env)
+		default:
+			return fmt.Sprintf("unsupported key type %s at %s", k.Type(), path)
+		}
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		writeJsonString(&e.buf, key)
+		e.buf.WriteByte(':')
+		if e.indent != "" {
+			e.buf.WriteByte(' ')
+		}
+		if msg := e.encode(pairValue(pair), path+"."+key, depth+1); msg != "" {
+			return msg
+		}
+	}
+	e.newline(depth)
+	e.buf.WriteByte('}')
+	return ""
+}
+
+func writeJsonString(buf *bytes.Buffer, str string) {
+	buf.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// jsonDecoder reads one JSON value from src, keeping the order of the keys
+// of objects.
+type jsonDecoder struct {
+	src string
+	pos int
+}
+
+func (d *jsonDecoder) errorf(format string, a ...interface{}) string {
+	line, column := 1, 1
+	for _, r := range d.src[:d.pos] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Sprintf(format, a...) + fmt.Sprintf(" at line %d, column %d", line, column)
+}
+
+func (d *jsonDecoder) unexpected() string {
+	if d.pos >= len(d.src) {
+		return d.errorf("unexpected end of input")
+	}
+	r, _ := utf8.DecodeRuneInString(d.src[d.pos:])
+	return d.errorf("unexpected character %q", r)
+}
+
+func (d *jsonDecoder) skipSpace() {
+	for d.pos < len(d.src) {
+		switch d.src[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *jsonDecoder) consume(c byte) bool {
+	d.skipSpace()
+	if d.pos < len(d.src) && d.src[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *jsonDecoder) decode() (Object, string) {
+	d.skipSpace()
+	if d.pos >= len(d.src) {
+		return nil, d.unexpected()
+	}
+	switch c := d.src[d.pos]; {
+	case c == '{':
+		return d.decodeObject()
+	case c == '[':
+		return d.decodeArray()
+	case c == '"':
+		str, msg := d.decodeString()
+		if msg != "" {
+			return nil, msg
+		}
+		return &String{Value: []rune(str)}, ""
+	case c == '-' || c >= '0' && c <= '9':
+		return d.decodeNumber()
+	}
+	for word, value := range map[string]Object{"true": TrueObj, "false": FalseObj, "null": VoidObj} {
+		if strings.HasPrefix(d.src[d.pos:], word) {
+			d.pos += len(word)
+			return value, ""
+		}
+	}
+	return nil, d.unexpected()
+}
+
+func (d *jsonDecoder) decodeObject() (Object, string) {
+	d.pos++
+	hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+	if d.consume('}') {
+		return hash, ""
+	}
+	for {
+		d.skipSpace()
+		if d.pos >= len(d.src) || d.src[d.pos] != '"' {
+			return nil, d.unexpected()
+		}
+		str, msg := d.decodeString()
+		if msg != "" {
+			return nil, msg
+		}
+		if !d.consume(':') {
+			return nil, d.unexpected()
+		}
+		value, msg := d.decode()
+		if msg != "" {
+			return nil, msg
+		}
+		key := &String{Value: []rune(str)}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &value})
+		if d.consume('}') {
+			return hash, ""
+		}
+		if !d.consume(',') {
+			return nil, d.unexpected()
+		}
+	}
+}
+
+func (d *jsonDecoder) decodeArray() (Object, string) {
+	d.pos++
+	arr := &Array{Elements: []Object{}, Xvalue: true}
+	if d.consume(']') {
+		return arr, ""
+	}
+	for {
+		value, msg := d.decode()
+		if msg != "" {
+			return nil, msg
+		}
+		arr.Elements = append(arr.Elements, value)
+		if d.consume(']') {
+			return arr, ""
+		}
+		if !d.consume(',') {
+			return nil, d.unexpected()
+		}
+	}
+}
+
+func (d *jsonDecoder) decodeString() (string, string) {
+	d.pos++
+	var out strings.Builder
+	for d.pos < len(d.src) {
+		c := d.src[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return out.String(), ""
+		case c < 0x20:
+			return "", d.unexpected()
+		case c != '\\':
+			r, size := utf8.DecodeRuneInString(d.src[d.pos:])
+			out.WriteRune(r)
+			d.pos += size
+			continue
+		}
+		d.pos++
+		if d.pos >= len(d.src) {
+			return "", d.unexpected()
+		}
+		switch d.src[d.pos] {
+		case '"', '\\', '/':
+			out.WriteByte(d.src[d.pos])
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'u':
+			r, msg := d.decodeHex()
+			if msg != "" {
+				return "", msg
+			}
+			if utf16.IsSurrogate(r) && strings.HasPrefix(d.src[d.pos+1:], `\u`) {
+				d.pos += 2
+				low, msg := d.decodeHex()
+				if msg != "" {
+					return "", msg
+				}
+				r = utf16.DecodeRune(r, low)
+			}
+			out.WriteRune(r)
+		default:
+			return "", d.errorf("invalid escape %q", d.src[d.pos-1:d.pos+1])
+		}
+		d.pos++
+	}
+	return "", d.unexpected()
+}
+
+// decodeHex reads the 4 hex digits after the 'u' at pos, and leaves pos on
+// the last of them.
+func (d *jsonDecoder) decodeHex() (rune, string) {
+	if d.pos+5 > len(d.src) {
+		d.pos = len(d.src)
+		return 0, d.unexpected()
+	}
+	n, err := strconv.ParseUint(d.src[d.pos+1:d.pos+5], 16, 32)
+	if err != nil {
+		return 0, d.errorf("invalid escape %q", d.src[d.pos-1:d.pos+5])
+	}
+	d.pos += 4
+	return rune(n), ""
+}
+
+func (d *jsonDecoder) decodeNumber() (Object, string) {
+	start := d.pos
+	isFloat := false
+	digits := func() bool {
+		begin := d.pos
+		for d.pos < len(d.src) && d.src[d.pos] >= '0' && d.src[d.pos] <= '9' {
+			d.pos++
+		}
+		return d.pos > begin
+	}
+	if d.src[d.pos] == '-' {
+		d.pos++
+	}
+	if d.pos < len(d.src) && d.src[d.pos] == '0' {
+		d.pos++
+	} else if !digits() {
+		return nil, d.unexpected()
+	}
+	if d.pos < len(d.src) && d.src[d.pos] == '.' {
+		isFloat = true
+		d.pos++
+		if !digits() {
+			return nil, d.unexpected()
+		}
+	}
+	if d.pos < len(d.src) && (d.src[d.pos] == 'e' || d.src[d.pos] == 'E') {
+		isFloat = true
+		d.pos++
+		if d.pos < len(d.src) && (d.src[d.pos] == '+' || d.src[d.pos] == '-') {
+			d.pos++
+		}
+		if !digits() {
+			return nil, d.unexpected()
+		}
+	}
+	text := d.src[start:d.pos]
+	if isFloat {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			d.pos = start
+			return nil, d.errorf("number %s out of range", text)
+		}
+		return &Float{Value: f}, ""
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return &Integer{Value: n}, ""
+	}
+	b, _ := new(big.Int).SetString(text, 10)
+	return NewBigInteger(b), ""
+}
+
+func newJsonModule() *Hash {
+	return newModule(map[string]Object{
+		// json.encode(value, indent) writes value as JSON, indent is a number
+		// of spaces or a String, and leaves the JSON on one line when omitted
+		"encode": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function json.encode: len(args) should be 1 to 2")
+			}
+			e := &jsonEncoder{visiting: map[Object]bool{}, env: env}
+			if len(args) == 2 {
+				switch indent := UnwrapReferenceValue(args[1]).(type) {
+				case *Integer:
+					if indent.Big != nil || indent.Value < 0 || indent.Value > 16 {
+						return newError("native function json.encode: indent should be 0 to 16")
+					}
+					e.indent = strings.Repeat(" ", int(indent.Value))
+				case *String:
+					e.indent = string(indent.Value)
+				default:
+					return newError("native function json.encode: args[1] should be Integer or String")
+				}
+			}
+			if msg := e.encode(args[0], "$", 0); msg != "" {
+				return newError("native function json.encode: %s", msg)
+			}
+			return &String{Value: []rune(e.buf.String())}
+		}},
+		"decode": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function json.decode: len(args) should be 1")
+			}
+			str, err := stringArg("json.decode", args, 0)
+			if err != nil {
+				return err
+			}
+			d := &jsonDecoder{src: string(str)}
+			value, msg := d.decode()
+			if msg == "" {
+				d.skipSpace()
+				if d.pos < len(d.src) {
+					msg = d.unexpected()
+				}
+			}
+			if msg != "" {
+				return newError("native function json.decode: %s", msg)
+			}
+			return value
+		}},
+	})
+}
@@ -0,0 +1,32 @@
+package evaluator
+
+// interpreter is the lock of the interpreter, T code only runs in the
+// goroutine holding it. The goroutine running the script holds it from the
+// start, so the channel is empty until it is released. Natives which wait on
+// the outside world, e.g. http.get, process.run, fs.read and time.sleep,
+// release it while waiting, which is when the handlers of servers get to run.
+var interpreter = make(chan struct{}, 1)
+
+func acquireInterpreter() {
+	<-interpreter
+}
+
+func releaseInterpreter() {
+	interpreter <- struct{}{}
+}
+
+// blocking runs fn without holding the interpreter, fn must not touch any
+// Object or Environment.
+func blocking(fn func()) {
+	releaseInterpreter()
+	defer acquireInterpreter()
+	fn()
+}
+
+// inInterpreter runs fn holding the interpreter, from a goroutine other than
+// the one running the script.
+func inInterpreter(fn func()) {
+	acquireInterpreter()
+	defer releaseInterpreter()
+	fn()
+}
@@ -0,0 +1,215 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+)
+
+func floatArg(name string, args []Object, i int) (float64, Object) {
+	if i < len(args) {
+		switch arg := UnwrapReferenceValue(args[i]).(type) {
+		case *Integer:
+			return arg.Float(), nil
+		case *Float:
+			return arg.Value, nil
+		case *Decimal:
+			return arg.Float(), nil
+		}
+	}
+	return 0, newError("native function math.%s: args[%d] should be Integer or Float", name, i)
+}
+
+func newMathNative(name string, fn func(x float64) float64) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function math.%s: len(args) should be 1", name)
+		}
+		x, err := floatArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return &Float{Value: fn(x)}
+	}}
+}
+
+func newMathNative2(name string, fn func(x, y float64) float64) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 2 {
+			return newError("native function math.%s: len(args) should be 2", name)
+		}
+		x, err := floatArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		y, err := floatArg(name, args, 1)
+		if err != nil {
+			return err
+		}
+		return &Float{Value: fn(x, y)}
+	}}
+}
+
+// maxPowBits bounds the size of the Integer results of math.pow.
+const maxPowBits = 1 << 20
+
+// newRoundNative keeps an Integer as it is, rounds a Float with fn, and a
+// Decimal to scale 0 with the rounding mode of the same meaning.
+func newRoundNative(name string, fn func(x float64) float64, mode string) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function math.%s: len(args) should be 1", name)
+		}
+		switch arg := UnwrapReferenceValue(args[0]).(type) {
+		case *Integer:
+			return arg
+		case *Float:
+			return &Float{Value: fn(arg.Value)}
+		case *Decimal:
+			return roundDecimal(new(big.Rat).SetFrac(arg.Value, pow10(arg.Scale)), 0, mode)
+		}
+		return newError("native function math.%s: args[0] should be Integer or Float", name)
+	}}
+}
+
+// newExtremeNative picks from its arguments, or from the elements of a single
+// Array argument, the one for which operator holds against all others.
+func newExtremeNative(name, operator string) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) == 1 {
+			if arr, ok := UnwrapReferenceValue(args[0]).(*Array); ok {
+				args = arr.Elements
+			}
+		}
+		if len(args) == 0 {
+			return VoidObj
+		}
+		extreme := UnwrapReferenceValue(args[0])
+		for _, arg := range args[1:] {
+			arg = UnwrapReferenceValue(arg)
+			result := evalInfixExpression(operator, arg, extreme, env)
+			if isError(result) {
+				return newError("native function math.%s: %s", name, result.(*Err).Message)
+			}
+			if isTruthy(result) {
+				extreme = arg
+			}
+		}
+		return extreme
+	}}
+}
+
+func newMathModule() *Hash {
+	return newModule(map[string]Object{
+		"pi":     &Float{Value: math.Pi},
+		"e":      &Float{Value: math.E},
+		"inf":    &Float{Value: math.Inf(1)},
+		"nan":    &Float{Value: math.NaN()},
+		"maxInt": &Integer{Value: math.MaxInt64},
+		"minInt": &Integer{Value: math.MinInt64},
+
+		"sqrt":  newMathNative("sqrt", math.Sqrt),
+		"cbrt":  newMathNative("cbrt", math.Cbrt),
+		"exp":   newMathNative("exp", math.Exp),
+		"log2":  newMathNative("log2", math.Log2),
+		"log10": newMathNative("log10", math.Log10),
+		"sin":   newMathNative("sin", math.Sin),
+		"cos":   newMathNative("cos", math.Cos),
+		"tan":   newMathNative("tan", math.Tan),
+		"asin":  newMathNative("asin", math.Asin),
+		"acos":  newMathNative("acos", math.Acos),
+		"atan":  newMathNative("atan", math.Atan),
+		"sinh":  newMathNative("sinh", math.Sinh),
+		"cosh":  newMathNative("cosh", math.Cosh),
+		"tanh":  newMathNative("tanh", math.Tanh),
+		"atan2": newMathNative2("atan2", math.Atan2),
+		"hypot": newMathNative2("hypot", math.Hypot),
+		"floor": newRoundNative("floor", math.Floor, "floor"),
+		"ceil":  newRoundNative("ceil", math.Ceil, "ceiling"),
+		"round": newRoundNative("round", math.Round, "halfUp"),
+		"trunc": newRoundNative("trunc", math.Trunc, "down"),
+		"max":   newExtremeNative("max", ">"),
+		"min":   newExtremeNative("min", "<"),
+		"log": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function math.log: len(args) should be 1 or 2")
+			}
+			x, err := floatArg("log", args, 0)
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return &Float{Value: math.Log(x)}
+			}
+			base, err := floatArg("log", args, 1)
+			if err != nil {
+				return err
+			}
+			return &Float{Value: math.Log(x) / math.Log(base)}
+		}},
+		"pow": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 2 {
+				return newError("native function math.pow: len(args) should be 2")
+			}
+			base, baseInt := UnwrapReferenceValue(args[0]).(*Integer)
+			exp, expInt := UnwrapReferenceValue(args[1]).(*Integer)
+			if baseInt && expInt && exp.BigInt().Sign() >= 0 {
+				// 0, 1 and -1 stay small, the result of other bases has more
+				// than exp*(bits-1) bits
+				if bits := int64(base.BigInt().BitLen()); bits > 1 &&
+					(exp.Big != nil || exp.Value > maxPowBits/(bits-1)) {
+					return newError("native function math.pow: result has more than %d bits", maxPowBits)
+				}
+				return NewBigInteger(new(big.Int).Exp(base.BigInt(), exp.BigInt(), nil))
+			}
+			x, err := floatArg("pow", args, 0)
+			if err != nil {
+				return err
+			}
+			y, err := floatArg("pow", args, 1)
+			if err != nil {
+				return err
+			}
+			return &Float{Value: math.Pow(x, y)}
+		}},
+		"abs": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function math.abs: len(args) should be 1")
+			}
+			switch arg := UnwrapReferenceValue(args[0]).(type) {
+			case *Integer:
+				if arg.Big == nil && arg.Value != math.MinInt64 {
+					if arg.Value < 0 {
+						return &Integer{Value: -arg.Value}
+					}
+					return arg
+				}
+				return NewBigInteger(new(big.Int).Abs(arg.BigInt()))
+			case *Float:
+				return &Float{Value: math.Abs(arg.Value)}
+			case *Decimal:
+				return &Decimal{Value: new(big.Int).Abs(arg.Value), Scale: arg.Scale}
+			}
+			return newError("native function math.abs: args[0] should be Integer or Float")
+		}},
+		"isNaN": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function math.isNaN: len(args) should be 1")
+			}
+			x, err := floatArg("isNaN", args, 0)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(math.IsNaN(x))
+		}},
+		"isInf": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function math.isInf: len(args) should be 1")
+			}
+			x, err := floatArg("isInf", args, 0)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(math.IsInf(x, 0))
+		}},
+	})
+}
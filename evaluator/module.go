@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"github.com/mark07x/TLang/ast"
+	"github.com/mark07x/TLang/lexer"
+	"github.com/mark07x/TLang/parser"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ImportPaths are searched for modules which are not next to the importing
+// file, in order. They start as the paths in TLANG_PATH.
+var ImportPaths = filepath.SplitList(os.Getenv("TLANG_PATH"))
+
+// modules caches the modules by the absolute path of their file with symbolic
+// links followed, so a module is evaluated once however it is reached, the
+// first time it is imported. loading has the modules being evaluated, which
+// are closed when done, since handlers of servers may import the same module
+// while the interpreter is released.
+var modules = map[string]Object{}
+var loading = map[string]chan struct{}{}
+
+// NewScriptEnvironment makes the environment to run a script in, starting
+// with the built-in prototypes as they are before any script changes them,
+// and with its own decimal context.
+func NewScriptEnvironment() *Environment {
+	resetPrototypes()
+	env := SharedEnv.NewEnclosedEnvironment()
+	env.decimal = newDecimalContext()
+	return env
+}
+
+// NewFileEnvironment makes the environment to run the script at path in, the
+// modules it imports are found relative to it.
+func NewFileEnvironment(path string) *Environment {
+	env := NewScriptEnvironment()
+	env.file = modulePath(path)
+	return env
+}
+
+// resolveModule finds the file of module name imported from env. "./a.t"
+// and "../a.t" are relative to the importing file, "a.t" is also searched in
+// ImportPaths, and ".t" may be omitted.
+func resolveModule(name string, env *Environment) (string, Object) {
+	base, _ := os.Getwd()
+	if file := env.File(); file != "" {
+		base = filepath.Dir(file)
+	}
+	var dirs []string
+	switch {
+	case filepath.IsAbs(name):
+		dirs = []string{""}
+	case strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../"):
+		dirs = []string{base}
+	default:
+		dirs = append([]string{base}, ImportPaths...)
+	}
+
+	var denied Object
+	var searched []string
+	for _, dir := range dirs {
+		candidates := []string{filepath.Join(dir, name)}
+		if filepath.Ext(name) == "" {
+			candidates = append(candidates, candidates[0]+".t")
+		}
+		for _, candidate := range candidates {
+			path, err := DefaultSandbox.CheckPath("import", CapRead, candidate)
+			if err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
+			searched = append(searched, path)
+			if info, e := os.Stat(path); e == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+	}
+	if denied != nil {
+		return "", denied
+	}
+	return "", newError("native function import: module %s not found in %s", name, strings.Join(searched, ", "))
+}
+
+// moduleExports is what importing a module gives, the value of export when
+// the module has it, otherwise a Hash of its top-level names, except those
+// starting with "_". The Hash shares the variables with the module.
+func moduleExports(env *Environment) Object {
+	if export, ok := (*env.store)["export"]; ok {
+		return *export
+	}
+	names := make([]string, 0, len(*env.store))
+	for name := range *env.store {
+		if !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	exports := &Hash{Pairs: make(map[HashKey]HashPair, len(names))}
+	for _, name := range names {
+		key := &String{Value: []rune(name)}
+		exports.Set(key.HashKey(), HashPair{Key: key, Value: (*env.store)[name]})
+	}
+	return exports
+}
+
+// modulePath is the absolute path of file with its symbolic links followed.
+func modulePath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return real
+	}
+	return abs
+}
+
+func importModule(name string, env *Environment) Object {
+	file, err := resolveModule(name, env)
+	if err != nil {
+		return err
+	}
+	path := modulePath(file)
+	importing := env.Importing()
+	for i, file := range importing {
+		if file == path {
+			return newError("native function import: circular import: %s",
+				strings.Join(append(importing[i:len(importing):len(importing)], path), " -> "))
+		}
+	}
+	for {
+		if module, ok := modules[path]; ok {
+			return module
+		}
+		done, ok := loading[path]
+		if !ok {
+			break
+		}
+		// another goroutine is evaluating it, it is cached after that, unless
+		// it failed, which is then found again here
+		blocking(func() { <-done })
+	}
+	done := make(chan struct{})
+	loading[path] = done
+	defer func() {
+		delete(loading, path)
+		close(done)
+	}()
+
+	var data []byte
+	var e error
+	blocking(func() { data, e = ioutil.ReadFile(path) })
+	if e != nil {
+		return newError("native function import: %s", e.Error())
+	}
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("native function import: parser errors in %s: %s", path, strings.Join(p.Errors(), "; "))
+	}
+
+	moduleEnv := SharedEnv.NewEnclosedEnvironment()
+	moduleEnv.file = path
+	moduleEnv.decimal = newDecimalContext()
+	moduleEnv.importing = append(importing[:len(importing):len(importing)], path)
+	result := Eval(program, moduleEnv)
+	if isError(result) {
+		return result
+	}
+	modules[path] = moduleExports(moduleEnv)
+	return modules[path]
+}
+
+// evalImportStatement binds the module, or the names taken from it, in env
+// like let does.
+func evalImportStatement(node *ast.ImportStatement, env *Environment) Object {
+	module := importModule(node.Path.Value, env)
+	if isError(module) {
+		return module
+	}
+	if node.Alias != nil {
+		if _, ok := env.SetCurrent(node.Alias.Value, module.Copy()); !ok {
+			return newError("identifier %s already set", node.Alias.Value)
+		}
+		return nil
+	}
+
+	hash, ok := module.(*Hash)
+	if !ok {
+		return newError("import: %s does not export a Hash", node.Path.Value)
+	}
+	for i, name := range node.Names {
+		pair, ok := hash.Pairs[(&String{Value: []rune(name.Value)}).HashKey()]
+		if !ok {
+			return newError("import: %s has no name %s", node.Path.Value, name.Value)
+		}
+		bind := name
+		if node.Aliases[i] != nil {
+			bind = node.Aliases[i]
+		}
+		if _, ok := env.SetCurrent(bind.Value, (*pair.Value).Copy()); !ok {
+			return newError("identifier %s already set", bind.Value)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,22 @@
 package evaluator
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/csv"
 	"fmt"
 	"github.com/mark07x/TLang/ast"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Type string
@@ -29,8 +40,10 @@ const (
 const (
 	INTEGER     Type = "Integer"
 	FLOAT       Type = "Float"
+	DECIMAL     Type = "Decimal"
 	BOOLEAN     Type = "Boolean"
 	STRING      Type = "String"
+	BYTES       Type = "Bytes"
 	CHARACTER   Type = "Character"
 	VOID        Type = "Void"
 	RET         Type = "Ret"
@@ -41,8 +54,18 @@ const (
 	UNDERLINE   Type = "Underline"
 	NATIVE      Type = "Native"
 	ARRAY       Type = "Array"
+	TUPLE       Type = "Tuple"
+	SET         Type = "Set"
 	REFERENCE   Type = "Reference"
 	HASH        Type = "Hash"
+	SLICE       Type = "Slice"
+	RANDOM      Type = "Random"
+	TIME        Type = "Time"
+	FILE        Type = "File"
+	REGEX       Type = "Regex"
+	CSVREADER   Type = "CsvReader"
+	SERVER      Type = "Server"
+	SOCKET      Type = "Socket"
 	ENVIRONMENT Type = "Environment"
 )
 
@@ -76,6 +99,9 @@ func UnwrapArrayReferenceValue(obj Object) Object {
 func UnwrapReferenceValue(obj Object) Object {
 	if referenceVal, ok := obj.(*Reference); ok {
 		if referenceVal.Value == nil {
+			if referenceVal.Method != nil {
+				return referenceVal.Method
+			}
 			return VoidObj
 		}
 		if fun, ok := (*referenceVal.Value).(*Function); ok {
@@ -119,21 +145,63 @@ type Allocable interface {
 	Free(Index Object) bool
 }
 
+// Iterator gives the values of loop in one by one, Next returns false after
+// the last value.
+type Iterator interface {
+	Object
+	Next(env *Environment) (Object, bool)
+}
+
 type HashKey struct {
 	Type  Type
 	Value interface{}
 }
 
+// Integer holds an int64 in Value. Results which do not fit in int64 are
+// promoted to Big, and Value holds the saturated int64 value. Code reading
+// Value should reject a Big Integer first, as integerArg does.
 type Integer struct {
 	Value int64
+	Big   *big.Int
+}
+
+func NewBigInteger(b *big.Int) *Integer {
+	if b.IsInt64() {
+		return &Integer{Value: b.Int64()}
+	}
+	if b.Sign() > 0 {
+		return &Integer{Value: math.MaxInt64, Big: b}
+	}
+	return &Integer{Value: math.MinInt64, Big: b}
+}
+func (i *Integer) BigInt() *big.Int {
+	if i.Big != nil {
+		return i.Big
+	}
+	return big.NewInt(i.Value)
+}
+func (i *Integer) Float() float64 {
+	if i.Big != nil {
+		f, _ := new(big.Float).SetInt(i.Big).Float64()
+		return f
+	}
+	return float64(i.Value)
 }
 
-func (i *Integer) Inspect(num int, env *Environment) string { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) Inspect(num int, env *Environment) string {
+	if i.Big != nil {
+		return i.Big.String()
+	}
+	return fmt.Sprintf("%d", i.Value)
+}
 func (i *Integer) Type() Type             { return INTEGER }
 func (i *Integer) TypeC() TypeC           { return INT64 }
 func (i *Integer) Copy() Object           { return i }
 func (i *Integer) NumberObj()             {}
 func (i *Integer) HashKey() HashKey {
+	if i.Big != nil {
+		return HashKey{Type: i.Type(), Value: i.Big.String()}
+	}
 	return HashKey{Type: i.Type(), Value: i.Value}
 }
 
@@ -146,6 +214,58 @@ func (f *Float) Type() Type             { return FLOAT }
 func (f *Float) TypeC() TypeC           { return FLOAT64 }
 func (f *Float) Copy() Object           { return f }
 func (f *Float) NumberObj()             {}
+// HashKey is the key of the equal Decimal, and so of the equal Integer, since
+// == compares them as equal, e.g. {1: x}[1.0] is x.
+func (f *Float) HashKey() HashKey {
+	if math.IsNaN(f.Value) {
+		return HashKey{Type: f.Type(), Value: math.Float64bits(math.NaN())}
+	}
+	if math.IsInf(f.Value, 0) {
+		return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+	}
+	d, _ := toDecimal(f)
+	return d.HashKey()
+}
+
+// Decimal holds the exact value Value * 10^-Scale.
+type Decimal struct {
+	Value *big.Int
+	Scale int32
+}
+
+func (d *Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Value, pow10(d.Scale))
+}
+func (d *Decimal) Float() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+func (d *Decimal) Inspect(num int, env *Environment) string {
+	digits := new(big.Int).Abs(d.Value).String()
+	for int32(len(digits)) <= d.Scale {
+		digits = "0" + digits
+	}
+	if d.Scale > 0 {
+		digits = digits[:int32(len(digits))-d.Scale] + "." + digits[int32(len(digits))-d.Scale:]
+	}
+	if d.Value.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+func (d *Decimal) Type() Type   { return DECIMAL }
+func (d *Decimal) TypeC() TypeC { return INVALID }
+func (d *Decimal) Copy() Object { return d }
+func (d *Decimal) NumberObj()   {}
+// HashKey of an integral Decimal is the one of the equal Integer.
+func (d *Decimal) HashKey() HashKey {
+	r := d.Rat()
+	if r.IsInt() {
+		return NewBigInteger(r.Num()).HashKey()
+	}
+	return HashKey{Type: d.Type(), Value: r.RatString()}
+}
 
 type Boolean struct {
 	Value bool
@@ -165,7 +285,7 @@ func (v *Void) Inspect(num int, env *Environment) string { return "void" }
 func (v *Void) Type() Type             { return VOID }
 func (v *Void) TypeC() TypeC           { return INT }
 func (v *Void) Copy() Object           { return v }
-func (v *Void) HashAble() HashKey {
+func (v *Void) HashKey() HashKey {
 	return HashKey{Type: v.Type(), Value: 0}
 }
 
@@ -281,6 +401,153 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: string(s.Value)}
 }
 
+type Bytes struct {
+	Value []byte
+}
+
+func (b *Bytes) Inspect(num int, env *Environment) string {
+	var out bytes.Buffer
+
+	out.WriteString("b\"")
+	for _, c := range b.Value {
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			out.WriteByte(c)
+		default:
+			out.WriteString(fmt.Sprintf("\\x%02x", c))
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+func (b *Bytes) Type() Type   { return BYTES }
+func (b *Bytes) TypeC() TypeC { return POINTER }
+func (b *Bytes) Copy() Object { return b }
+func (b *Bytes) HashKey() HashKey {
+	return HashKey{Type: b.Type(), Value: string(b.Value)}
+}
+
+// Random is a generator of pseudo random numbers, which always gives the same
+// numbers for the same seed.
+type Random struct {
+	Rand *rand.Rand
+	Seed int64
+}
+
+func (r *Random) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("random(%d)", r.Seed)
+}
+func (r *Random) Type() Type   { return RANDOM }
+func (r *Random) TypeC() TypeC { return INVALID }
+func (r *Random) Copy() Object { return r }
+
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("time(%q)", t.Value.Format(time.RFC3339Nano))
+}
+func (t *Time) Type() Type   { return TIME }
+func (t *Time) TypeC() TypeC { return INVALID }
+func (t *Time) Copy() Object { return t }
+func (t *Time) HashKey() HashKey {
+	return HashKey{Type: t.Type(), Value: t.Value.UTC().Format(time.RFC3339Nano)}
+}
+
+// File is a file opened by fs.open, Reader is nil unless it is opened for
+// reading, and File is nil after it is closed.
+type File struct {
+	File   *os.File
+	Reader *bufio.Reader
+	Path   string
+	Mode   string
+}
+
+func (f *File) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("file(%q, %q)", f.Path, f.Mode)
+}
+func (f *File) Type() Type   { return FILE }
+func (f *File) TypeC() TypeC { return INVALID }
+func (f *File) Copy() Object { return f }
+
+// Regex is a compiled regular expression, in the syntax of Go's regexp.
+type Regex struct {
+	Regexp *regexp.Regexp
+}
+
+func (r *Regex) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("regex(%q)", r.Regexp.String())
+}
+func (r *Regex) Type() Type   { return REGEX }
+func (r *Regex) TypeC() TypeC { return INVALID }
+func (r *Regex) Copy() Object { return r }
+func (r *Regex) HashKey() HashKey {
+	return HashKey{Type: r.Type(), Value: r.Regexp.String()}
+}
+
+// CsvReader reads the rows of a CSV file one by one, File is nil after the
+// last row is read or it is closed. Header is read with the first row when
+// the rows are read as Hashes.
+type CsvReader struct {
+	Reader    *csv.Reader
+	File      *os.File
+	Path      string
+	UseHeader bool
+	Header    []string
+}
+
+func (r *CsvReader) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("csvReader(%q)", r.Path)
+}
+func (r *CsvReader) Type() Type   { return CSVREADER }
+func (r *CsvReader) TypeC() TypeC { return INVALID }
+func (r *CsvReader) Copy() Object { return r }
+
+// Server is an HTTP server started by http.listen, Done is closed when it
+// stops serving.
+type Server struct {
+	Server   *http.Server
+	Listener net.Listener
+	Done     chan struct{}
+}
+
+func (s *Server) Inspect(num int, env *Environment) string {
+	return fmt.Sprintf("server(%q)", s.Listener.Addr().String())
+}
+func (s *Server) Type() Type   { return SERVER }
+func (s *Server) TypeC() TypeC { return INVALID }
+func (s *Server) Copy() Object { return s }
+
+// Socket is a TCP connection, or a listener when Listener is set. Conn and
+// Listener are nil after it is closed.
+// Socket is a connection, or a listener when Listener is set. Done is closed
+// when a listener served by serve stops accepting, and Err is the first error
+// of its handlers.
+type Socket struct {
+	Conn     net.Conn
+	Reader   *bufio.Reader
+	Listener *net.TCPListener
+	Addr     string
+	Remote   string
+	Done     chan struct{}
+	Err      Object
+}
+
+func (s *Socket) Inspect(num int, env *Environment) string {
+	if s.Remote == "" {
+		return fmt.Sprintf("socket(%q)", s.Addr)
+	}
+	return fmt.Sprintf("socket(%q, %q)", s.Addr, s.Remote)
+}
+func (s *Socket) Type() Type   { return SOCKET }
+func (s *Socket) TypeC() TypeC { return INVALID }
+func (s *Socket) Copy() Object { return s }
+
 type Character struct {
 	Value rune
 }
@@ -340,11 +607,125 @@ func (a *Array) Copy() Object {
 	return &Array{Elements: elements}
 }
 
+type Slice struct {
+	Start Object
+	End   Object
+	Step  Object
+}
+
+func (s *Slice) Inspect(num int, env *Environment) string {
+	var out bytes.Buffer
+
+	if s.Start != VoidObj {
+		out.WriteString(s.Start.Inspect(num, env))
+	}
+	out.WriteString(":")
+	if s.End != VoidObj {
+		out.WriteString(s.End.Inspect(num, env))
+	}
+	if s.Step != VoidObj {
+		out.WriteString(":")
+		out.WriteString(s.Step.Inspect(num, env))
+	}
+
+	return out.String()
+}
+func (s *Slice) Type() Type   { return SLICE }
+func (s *Slice) TypeC() TypeC { return INVALID }
+func (s *Slice) Copy() Object { return s }
+
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Inspect(num int, env *Environment) string {
+	if num <= 0 {
+		return "(...)"
+	}
+	var out bytes.Buffer
+
+	var elements []string
+	for _, e := range t.Elements {
+		elements = append(elements, e.Inspect(num-1, env))
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+func (t *Tuple) Type() Type   { return TUPLE }
+func (t *Tuple) TypeC() TypeC { return INVALID }
+func (t *Tuple) Copy() Object { return t }
+func (t *Tuple) HashKey() HashKey {
+	var keys []string
+	for _, e := range t.Elements {
+		key := e.(HashAble).HashKey()
+		keys = append(keys, fmt.Sprintf("%s(%#v)", key.Type, key.Value))
+	}
+	return HashKey{Type: t.Type(), Value: strings.Join(keys, ",")}
+}
+
+type Set struct {
+	Elements []Object
+	Keys     map[HashKey]int
+}
+
+func NewSet() *Set {
+	return &Set{Elements: []Object{}, Keys: make(map[HashKey]int)}
+}
+func (s *Set) Add(obj HashAble) {
+	key := obj.HashKey()
+	if _, ok := s.Keys[key]; !ok {
+		s.Keys[key] = len(s.Elements)
+		s.Elements = append(s.Elements, obj)
+	}
+}
+func (s *Set) Contains(obj HashAble) bool {
+	_, ok := s.Keys[obj.HashKey()]
+	return ok
+}
+
+func (s *Set) Inspect(num int, env *Environment) string {
+	if num <= 0 {
+		return "set(...)"
+	}
+	var out bytes.Buffer
+
+	var elements []string
+	for _, e := range s.Elements {
+		elements = append(elements, e.Inspect(num-1, env))
+	}
+
+	out.WriteString("set(")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+func (s *Set) Type() Type   { return SET }
+func (s *Set) TypeC() TypeC { return INVALID }
+func (s *Set) Copy() Object { return s }
+func (s *Set) HashKey() HashKey {
+	var keys []string
+	for key := range s.Keys {
+		keys = append(keys, fmt.Sprintf("%s(%#v)", key.Type, key.Value))
+	}
+	sort.Strings(keys)
+	return HashKey{Type: s.Type(), Value: strings.Join(keys, ",")}
+}
+
 type Reference struct {
 	Value  *Object
 	Origin Allocable
 	Index  Object
 	Const  bool
+	// Method is the prototype method seen through a key not allocated yet
+	Method Object
 }
 
 func (r *Reference) Inspect(num int, env *Environment) string {
@@ -374,7 +755,12 @@ type HashPair struct {
 }
 
 type Hash struct {
-	Pairs  map[HashKey]HashPair
+	Pairs map[HashKey]HashPair
+	// order keeps the keys in the order they are added, freed keys stay in
+	// it until it is compacted, index is the position of each key in order.
+	// Pairs should only be changed through Set and Free to keep them.
+	order  []HashKey
+	index  map[HashKey]int
 	Xvalue bool
 }
 
@@ -384,10 +770,10 @@ func (h *Hash) Alloc(Index Object) (*Object, bool) {
 		key := hashIndex.HashKey()
 		if _, ok := h.Pairs[key]; !ok {
 			var obj Object = nil
-			h.Pairs[key] = HashPair{
+			h.Set(key, HashPair{
 				Key:   hashIndex,
 				Value: &obj,
-			}
+			})
 			return &obj, true
 		}
 	}
@@ -398,12 +784,51 @@ func (h *Hash) Free(Index Object) bool {
 		key := hashIndex.HashKey()
 		if _, ok := h.Pairs[key]; ok {
 			delete(h.Pairs, key)
+			delete(h.index, key)
+			if len(h.order) > 2*len(h.index) {
+				h.compact()
+			}
 			return true
 		}
 	}
 	return false
 }
 
+// Set adds or replaces the pair of key, a new key goes after the others.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		if h.index == nil {
+			h.index = make(map[HashKey]int)
+		}
+		h.index[key] = len(h.order)
+		h.order = append(h.order, key)
+	}
+	h.Pairs[key] = pair
+}
+
+// compact drops the freed keys from order.
+func (h *Hash) compact() {
+	keys := h.order[:0]
+	for i, key := range h.order {
+		if pos, ok := h.index[key]; ok && pos == i {
+			h.index[key] = len(keys)
+			keys = append(keys, key)
+		}
+	}
+	h.order = keys
+}
+
+// Ordered returns the keys of h in the order they were added.
+func (h *Hash) Ordered() []HashKey {
+	keys := make([]HashKey, 0, len(h.Pairs))
+	for i, key := range h.order {
+		if pos, ok := h.index[key]; ok && pos == i {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 func (h *Hash) Inspect(num int, env *Environment) string {
 	ref := applyIndex(h, []Object{&String{Value: []rune("@inspect")}}, Default, env).(*Reference)
 	if ref.Value != nil {
@@ -416,7 +841,8 @@ func (h *Hash) Inspect(num int, env *Environment) string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, pair := range h.Pairs {
+	for _, key := range h.Ordered() {
+		pair := h.Pairs[key]
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(num - 1, env), (*pair.Value).Inspect(num - 1, env)))
 	}
@@ -434,17 +860,15 @@ func (h *Hash) Copy() Object {
 		h.Xvalue = false
 		return h
 	}
-	pairs := make(map[HashKey]HashPair)
-	for index, pair := range h.Pairs {
+	copied := &Hash{Pairs: make(map[HashKey]HashPair, len(h.Pairs))}
+	for _, key := range h.Ordered() {
+		pair := h.Pairs[key]
 		newVal := (*pair.Value).Copy()
-		pairs[index] = HashPair{
+		copied.Set(key, HashPair{
 			Key:   pair.Key,
 			Value: &newVal,
-		}
+		})
 	}
 
-	return &Hash{
-		Pairs:  pairs,
-		Xvalue: false,
-	}
+	return copied
 }
@@ -0,0 +1,197 @@
+package evaluator
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var osModule *Hash
+var scriptArgs []string
+
+// SetArgs gives the path of the script and the arguments after it to the
+// script, as os.script and os.args.
+func SetArgs(script string, args []string) {
+	scriptArgs = args
+	setMember(osModule, "script", &String{Value: []rune(script)})
+	setMember(osModule, "args", stringsToArray(args))
+}
+
+// parseFlags parses args with the flags in defaults, a flag takes the type of
+// its default value, and a Boolean flag takes no value unless given with "=".
+func parseFlags(defaults *Hash, args []string) (*Hash, []string, string) {
+	options := cloneHash(defaults)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		// "-" and negative numbers like "-5" are arguments, not flags
+		if len(arg) < 2 || arg[0] != '-' || arg[1] >= '0' && arg[1] <= '9' {
+			rest = append(rest, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg[1:], "-")
+		value, hasValue := "", false
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value, hasValue = name[:eq], name[eq+1:], true
+		}
+		key := &String{Value: []rune(name)}
+		pair, ok := options.Pairs[key.HashKey()]
+		if !ok {
+			return nil, nil, "unknown flag " + arg
+		}
+		def := UnwrapReferenceValue(*pair.Value)
+		if _, ok := def.(*Boolean); ok && !hasValue {
+			setMember(options, name, TrueObj)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, "flag " + arg + " needs a value"
+			}
+			i++
+			value = args[i]
+		}
+		var parsed Object
+		switch def.(type) {
+		case *Boolean:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, nil, "flag " + name + " should be Boolean: " + value
+			}
+			parsed = nativeBoolToBooleanObject(b)
+		case *Integer:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, nil, "flag " + name + " should be Integer: " + value
+			}
+			parsed = &Integer{Value: n}
+		case *Float:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, nil, "flag " + name + " should be Float: " + value
+			}
+			parsed = &Float{Value: f}
+		default:
+			parsed = &String{Value: []rune(value)}
+		}
+		setMember(options, name, parsed)
+	}
+	return options, rest, ""
+}
+
+func newOsModule() *Hash {
+	osModule = newModule(map[string]Object{
+		"script": &String{Value: []rune("")},
+		"args":   &Array{Elements: []Object{}},
+		"getenv": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function os.getenv: len(args) should be 1")
+			}
+			if err := DefaultSandbox.Check("os.getenv", CapEnv); err != nil {
+				return err
+			}
+			name, err := stringArg("os.getenv", args, 0)
+			if err != nil {
+				return err
+			}
+			if value, ok := os.LookupEnv(string(name)); ok {
+				return &String{Value: []rune(value)}
+			}
+			return VoidObj
+		}},
+		"setenv": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 2 {
+				return newError("native function os.setenv: len(args) should be 2")
+			}
+			if err := DefaultSandbox.Check("os.setenv", CapEnv); err != nil {
+				return err
+			}
+			name, err := stringArg("os.setenv", args, 0)
+			if err != nil {
+				return err
+			}
+			value, err := stringArg("os.setenv", args, 1)
+			if err != nil {
+				return err
+			}
+			if e := os.Setenv(string(name), string(value)); e != nil {
+				return newError("native function os.setenv: %s", e.Error())
+			}
+			return VoidObj
+		}},
+		"unsetenv": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function os.unsetenv: len(args) should be 1")
+			}
+			if err := DefaultSandbox.Check("os.unsetenv", CapEnv); err != nil {
+				return err
+			}
+			name, err := stringArg("os.unsetenv", args, 0)
+			if err != nil {
+				return err
+			}
+			if e := os.Unsetenv(string(name)); e != nil {
+				return newError("native function os.unsetenv: %s", e.Error())
+			}
+			return VoidObj
+		}},
+		"environ": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 0 {
+				return newError("native function os.environ: len(args) should be 0")
+			}
+			if err := DefaultSandbox.Check("os.environ", CapEnv); err != nil {
+				return err
+			}
+			environ := os.Environ()
+			sort.Strings(environ)
+			hash := &Hash{Pairs: map[HashKey]HashPair{}, Xvalue: true}
+			for _, kv := range environ {
+				if eq := strings.Index(kv, "="); eq > 0 {
+					setMember(hash, kv[:eq], &String{Value: []rune(kv[eq+1:])})
+				}
+			}
+			return hash
+		}},
+		"parseFlags": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function os.parseFlags: len(args) should be 1 or 2")
+			}
+			defaults, err := hashArg("os.parseFlags", args, 0)
+			if err != nil {
+				return err
+			}
+			var flagArgs []string
+			if len(args) == 2 {
+				arr, err := arrayArg("os.parseFlags", args, 1)
+				if err != nil {
+					return err
+				}
+				for i := range arr.Elements {
+					arg, err := stringArg("os.parseFlags", arr.Elements, i)
+					if err != nil {
+						return newError("native function os.parseFlags: args[1] should be Array of String")
+					}
+					flagArgs = append(flagArgs, string(arg))
+				}
+			} else {
+				flagArgs = scriptArgs
+			}
+			options, rest, msg := parseFlags(defaults, flagArgs)
+			if msg != "" {
+				return newError("native function os.parseFlags: %s", msg)
+			}
+			result := newModule(map[string]Object{
+				"options": options,
+				"args":    stringsToArray(rest),
+			})
+			result.Xvalue = true
+			return result
+		}},
+	})
+	return osModule
+}
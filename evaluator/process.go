@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// runOptions reads the options of process.run: "stdin" (String or Bytes),
+// "dir", "env" (a Hash added to the environment) and "timeout" in seconds.
+func runOptions(cmd *exec.Cmd, options *Hash) (time.Duration, Object) {
+	var timeout time.Duration
+	for _, pair := range ownPairs(options) {
+		name, ok := pair.Key.(*String)
+		if !ok {
+			return 0, newError("native function process.run: unknown option %s", pair.Key.Inspect(16, nil))
+		}
+		value := []Object{UnwrapReferenceValue(*pair.Value)}
+		switch string(name.Value) {
+		case "stdin":
+			data, err := dataArg("process.run: stdin", value, 0)
+			if err != nil {
+				return 0, newError("native function process.run: stdin should be String or Bytes")
+			}
+			cmd.Stdin = bytes.NewReader(data)
+		case "dir":
+			dir, err := stringArg("process.run: dir", value, 0)
+			if err != nil {
+				return 0, newError("native function process.run: dir should be String")
+			}
+			cmd.Dir = string(dir)
+		case "env":
+			env, ok := value[0].(*Hash)
+			if !ok {
+				return 0, newError("native function process.run: env should be Hash")
+			}
+			cmd.Env = os.Environ()
+			for _, pair := range ownPairs(env) {
+				key, ok := pair.Key.(*String)
+				val, err := stringArg("process.run: env", []Object{UnwrapReferenceValue(*pair.Value)}, 0)
+				if !ok || err != nil {
+					return 0, newError("native function process.run: env should be Hash of String")
+				}
+				cmd.Env = append(cmd.Env, string(key.Value)+"="+string(val))
+			}
+		case "timeout":
+			seconds, err := floatArg("process.run: timeout", value, 0)
+			if err != nil {
+				return 0, newError("native function process.run: timeout should be Integer or Float")
+			}
+			timeout = secondsToDuration(seconds)
+		default:
+			return 0, newError("native function process.run: unknown option %s", string(name.Value))
+		}
+	}
+	return timeout, nil
+}
+
+func newProcessModule() *Hash {
+	return newModule(map[string]Object{
+		"run": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function process.run: len(args) should be 1 or 2")
+			}
+			if err := DefaultSandbox.Check("process.run", CapExec); err != nil {
+				return err
+			}
+			arr, err := arrayArg("process.run", args, 0)
+			if err != nil {
+				return err
+			}
+			if len(arr.Elements) == 0 {
+				return newError("native function process.run: empty command")
+			}
+			var command []string
+			for i := range arr.Elements {
+				arg, err := stringArg("process.run", arr.Elements, i)
+				if err != nil {
+					return newError("native function process.run: args[0] should be Array of String")
+				}
+				command = append(command, string(arg))
+			}
+
+			cmd := exec.Command(command[0], command[1:]...)
+			var timeout time.Duration
+			if len(args) == 2 {
+				options, err := hashArg("process.run", args, 1)
+				if err != nil {
+					return err
+				}
+				if timeout, err = runOptions(cmd, options); err != nil {
+					return err
+				}
+			}
+			ctx := context.Background()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				timed := exec.CommandContext(ctx, command[0], command[1:]...)
+				timed.Stdin, timed.Dir, timed.Env = cmd.Stdin, cmd.Dir, cmd.Env
+				cmd = timed
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			var e error
+			blocking(func() { e = cmd.Run() })
+			code := int64(0)
+			timedOut := false
+			if e != nil {
+				exitErr, ok := e.(*exec.ExitError)
+				if !ok {
+					return newError("native function process.run: %s", e.Error())
+				}
+				code = int64(exitErr.ExitCode())
+				timedOut = ctx.Err() == context.DeadlineExceeded
+			}
+			result := newModule(map[string]Object{
+				"stdout": &String{Value: []rune(stdout.String())},
+				"stderr": &String{Value: []rune(stderr.String())},
+				// the Strings are decoded as utf-8, the Bytes keep binary output
+				"stdoutBytes": &Bytes{Value: stdout.Bytes()},
+				"stderrBytes": &Bytes{Value: stderr.Bytes()},
+				"code":        &Integer{Value: code},
+				"timedOut":    nativeBoolToBooleanObject(timedOut),
+			})
+			result.Xvalue = true
+			return result
+		}},
+	})
+}
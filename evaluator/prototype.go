@@ -0,0 +1,113 @@
+package evaluator
+
+// prototypes hold the methods of the built-in types. They are registered in
+// SharedEnv by the type name, so methods can be added from T code, e.g.
+// Array.sum = func(self) { ... }. NewScriptEnvironment makes them anew, so the
+// methods added by a script are not seen by the scripts run after it.
+var prototypes map[Type]*Hash
+
+var prototypeMethods = map[Type][]string{
+	INTEGER:   {"float", "decimal"},
+	FLOAT:     {"integer", "decimal"},
+	DECIMAL:   {"integer", "float"},
+	BOOLEAN:   {},
+	STRING:    {"len", "integer", "float", "decimal", "bytes"},
+	BYTES:     {"len"},
+	CHARACTER: {"integer"},
+	FUNC:      {"call"},
+	NATIVE:    {"call"},
+	ARRAY:     {"len", "append", "first", "last", "join"},
+	TUPLE:     {"len"},
+	SET:       {"len", "union", "intersect", "difference"},
+	HASH:      {"classType"},
+	RANDOM:    {},
+	TIME:      {},
+	FILE:      {},
+	REGEX:     {},
+	CSVREADER: {},
+	SERVER:    {},
+	SOCKET:    {},
+}
+
+// typeMethods are the natives only used as methods, taking self as args[0].
+var typeMethods = map[Type]map[string]*Native{
+	RANDOM:    randomMethods,
+	TIME:      timeMethods,
+	FILE:      fileMethods,
+	REGEX:     regexMethods,
+	CSVREADER: csvReaderMethods,
+	SERVER:    serverMethods,
+	SOCKET:    socketMethods,
+}
+
+func newPrototypes(env *Environment) map[Type]*Hash {
+	protos := map[Type]*Hash{}
+	for tp, names := range prototypeMethods {
+		members := map[string]Object{}
+		switch tp {
+		case STRING:
+			for name := range stringNatives {
+				if name != "join" {
+					names = append(names, name)
+				}
+			}
+		case ARRAY:
+			for name := range arrayNatives {
+				names = append(names, name)
+			}
+		case HASH:
+			for name := range hashNatives {
+				names = append(names, name)
+			}
+		}
+		for _, name := range append([]string{"string", "inspect", "type"}, names...) {
+			if val, ok := env.Get(name); ok {
+				members[name] = *val
+			}
+		}
+		for name, fn := range typeMethods[tp] {
+			members[name] = fn
+		}
+		protos[tp] = newModule(members)
+	}
+	return protos
+}
+
+// resetPrototypes replaces the prototypes in SharedEnv with new ones.
+func resetPrototypes() {
+	prototypes = newPrototypes(SharedEnv)
+	for tp, proto := range prototypes {
+		SharedEnv.Free(&String{Value: []rune(tp)})
+		SharedEnv.SetCurrent(string(tp), proto)
+	}
+}
+
+// prototypeMethod looks name up in the prototype of receiver's type and binds
+// receiver as self. Natives get receiver as their first argument, keeping the
+// Reference so that they can modify it.
+func prototypeMethod(receiver Object, name Object) (Object, bool) {
+	proto, ok := prototypes[UnwrapReferenceValue(receiver).Type()]
+	if !ok {
+		return nil, false
+	}
+	key, ok := name.(HashAble)
+	if !ok {
+		return nil, false
+	}
+	pair, ok := proto.Pairs[key.HashKey()]
+	if !ok || *pair.Value == nil {
+		return nil, false
+	}
+	switch method := UnwrapReferenceValue(*pair.Value).(type) {
+	case *Function:
+		bound := *method
+		bound.Self = UnwrapReferenceValue(receiver)
+		return &bound, true
+	case *Native:
+		return &Native{Fn: func(env *Environment, args []Object) Object {
+			return method.Fn(env, append([]Object{receiver}, args...))
+		}}, true
+	default:
+		return method, true
+	}
+}
@@ -0,0 +1,125 @@
+package evaluator
+
+import "math/rand"
+
+func newRandomNative(name string, min, max int, fn func(r *Random, args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function random.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function random.%s: len(args) should be %d to %d", name, min, max)
+		}
+		r, ok := UnwrapReferenceValue(args[0]).(*Random)
+		if !ok {
+			return newError("native function random.%s: args[0] should be Random", name)
+		}
+		return fn(r, args)
+	}}
+}
+
+func randomArrayArg(name string, args []Object, i int) ([]Object, Object) {
+	arr, err := arrayArg("random."+name, args, i)
+	if err != nil {
+		return nil, err
+	}
+	return elementsOf(arr), nil
+}
+
+// randomMethods are the methods of Random, the generator is args[0].
+var randomMethods = map[string]*Native{
+	"int": newRandomNative("int", 2, 3, func(r *Random, args []Object) Object {
+		lo, hi := int64(0), int64(0)
+		var err Object
+		if len(args) == 2 {
+			if hi, err = integerArg("random.int", args, 1); err != nil {
+				return err
+			}
+		} else {
+			if lo, err = integerArg("random.int", args, 1); err != nil {
+				return err
+			}
+			if hi, err = integerArg("random.int", args, 2); err != nil {
+				return err
+			}
+		}
+		if hi <= lo || hi-lo <= 0 {
+			return newError("native function random.int: empty range [%d, %d)", lo, hi)
+		}
+		return &Integer{Value: lo + r.Rand.Int63n(hi-lo)}
+	}),
+	"float": newRandomNative("float", 1, 3, func(r *Random, args []Object) Object {
+		lo, hi := 0.0, 1.0
+		var err Object
+		if len(args) == 2 {
+			if hi, err = floatArg("random.float", args, 1); err != nil {
+				return err
+			}
+		} else if len(args) == 3 {
+			if lo, err = floatArg("random.float", args, 1); err != nil {
+				return err
+			}
+			if hi, err = floatArg("random.float", args, 2); err != nil {
+				return err
+			}
+		}
+		return &Float{Value: lo + r.Rand.Float64()*(hi-lo)}
+	}),
+	"choice": newRandomNative("choice", 2, 2, func(r *Random, args []Object) Object {
+		elements, err := randomArrayArg("choice", args, 1)
+		if err != nil {
+			return err
+		}
+		if len(elements) == 0 {
+			return newError("native function random.choice: empty Array")
+		}
+		return elements[r.Rand.Intn(len(elements))]
+	}),
+	"shuffle": newRandomNative("shuffle", 2, 2, func(r *Random, args []Object) Object {
+		elements, err := randomArrayArg("shuffle", args, 1)
+		if err != nil {
+			return err
+		}
+		r.Rand.Shuffle(len(elements), func(i, j int) {
+			elements[i], elements[j] = elements[j], elements[i]
+		})
+		return &Array{Elements: elements, Xvalue: true}
+	}),
+	"sample": newRandomNative("sample", 3, 3, func(r *Random, args []Object) Object {
+		elements, err := randomArrayArg("sample", args, 1)
+		if err != nil {
+			return err
+		}
+		k, err := integerArg("random.sample", args, 2)
+		if err != nil {
+			return err
+		}
+		if k < 0 || k > int64(len(elements)) {
+			return newError("native function random.sample: sample size %d out of range", k)
+		}
+		sample := make([]Object, k)
+		for i, j := range r.Rand.Perm(len(elements))[:k] {
+			sample[i] = elements[j]
+		}
+		return &Array{Elements: sample, Xvalue: true}
+	}),
+}
+
+func newRandomModule() *Hash {
+	members := map[string]Object{
+		"new": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function random.new: len(args) should be 1")
+			}
+			seed, err := integerArg("random.new", args, 0)
+			if err != nil {
+				return err
+			}
+			return &Random{Rand: rand.New(rand.NewSource(seed)), Seed: seed}
+		}},
+	}
+	for name, fn := range randomMethods {
+		members[name] = fn
+	}
+	return newModule(members)
+}
@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"regexp"
+	"strings"
+)
+
+func newRegexNative(name string, min, max int, fn func(re *regexp.Regexp, str string, args []Object, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function regex.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function regex.%s: len(args) should be %d to %d", name, min, max)
+		}
+		re, ok := UnwrapReferenceValue(args[0]).(*Regex)
+		if !ok {
+			return newError("native function regex.%s: args[0] should be Regex", name)
+		}
+		str, err := stringArg("regex."+name, args, 1)
+		if err != nil {
+			return err
+		}
+		return fn(re.Regexp, string(str), args, env)
+	}}
+}
+
+// countArg returns the optional limit of matches at args[i], -1 for all.
+func countArg(name string, args []Object, i int) (int, Object) {
+	if i >= len(args) {
+		return -1, nil
+	}
+	n, err := integerArg(name, args, i)
+	return int(n), err
+}
+
+func compileRegex(name string, args []Object) Object {
+	pattern, err := stringArg(name, args, 0)
+	if err != nil {
+		return err
+	}
+	re, e := regexp.Compile(string(pattern))
+	if e != nil {
+		return newError("native function %s: %s", name, e.Error())
+	}
+	return &Regex{Regexp: re}
+}
+
+// groupsOf makes the whole match and the groups at loc into an Array, a
+// group which does not take part in the match is void.
+func groupsOf(str string, loc []int) *Array {
+	groups := make([]Object, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] < 0 {
+			groups[i] = VoidObj
+		} else {
+			groups[i] = &String{Value: []rune(str[loc[2*i]:loc[2*i+1]])}
+		}
+	}
+	return &Array{Elements: groups, Xvalue: true}
+}
+
+// regexMethods are the methods of Regex, the expression is args[0] and the
+// String to search is args[1].
+var regexMethods = map[string]*Native{
+	"match": newRegexNative("match", 2, 2, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		return nativeBoolToBooleanObject(re.MatchString(str))
+	}),
+	"find": newRegexNative("find", 2, 2, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		loc := re.FindStringIndex(str)
+		if loc == nil {
+			return VoidObj
+		}
+		return &String{Value: []rune(str[loc[0]:loc[1]])}
+	}),
+	"findAll": newRegexNative("findAll", 2, 3, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		n, err := countArg("regex.findAll", args, 2)
+		if err != nil {
+			return err
+		}
+		return stringsToArray(re.FindAllString(str, n))
+	}),
+	"groups": newRegexNative("groups", 2, 2, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		loc := re.FindStringSubmatchIndex(str)
+		if loc == nil {
+			return VoidObj
+		}
+		return groupsOf(str, loc)
+	}),
+	"groupsAll": newRegexNative("groupsAll", 2, 3, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		n, err := countArg("regex.groupsAll", args, 2)
+		if err != nil {
+			return err
+		}
+		matches := []Object{}
+		for _, loc := range re.FindAllStringSubmatchIndex(str, n) {
+			matches = append(matches, groupsOf(str, loc))
+		}
+		return &Array{Elements: matches, Xvalue: true}
+	}),
+	"named": newRegexNative("named", 2, 2, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		loc := re.FindStringSubmatchIndex(str)
+		if loc == nil {
+			return VoidObj
+		}
+		groups := groupsOf(str, loc).Elements
+		named := &Hash{Pairs: make(map[HashKey]HashPair)}
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				setMember(named, name, groups[i])
+			}
+		}
+		return named
+	}),
+	// replace expands $1 and ${name} in a String replacement, and calls a
+	// Functor replacement with the groups of each match
+	"replace": newRegexNative("replace", 3, 3, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		if repl, ok := UnwrapReferenceValue(args[2]).(Letter); ok {
+			r, err := stringArg("regex.replace", []Object{repl}, 0)
+			if err != nil {
+				return err
+			}
+			return &String{Value: []rune(re.ReplaceAllString(str, string(r)))}
+		}
+		fn, err := functorArg("regex.replace", args, 2)
+		if err != nil {
+			return newError("native function regex.replace: args[2] should be String or Functor")
+		}
+		var out strings.Builder
+		last := 0
+		for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+			result := callFunctor(fn, []Object{groupsOf(str, loc)}, env)
+			if isError(result) {
+				return result
+			}
+			replaced, ok := toString(result, env).(*String)
+			if !ok {
+				return newError("native function regex.replace: replacement should be String")
+			}
+			out.WriteString(str[last:loc[0]])
+			out.WriteString(string(replaced.Value))
+			last = loc[1]
+		}
+		out.WriteString(str[last:])
+		return &String{Value: []rune(out.String())}
+	}),
+	"split": newRegexNative("split", 2, 3, func(re *regexp.Regexp, str string, args []Object, env *Environment) Object {
+		n, err := countArg("regex.split", args, 2)
+		if err != nil {
+			return err
+		}
+		return stringsToArray(re.Split(str, n))
+	}),
+	"pattern": &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function regex.pattern: len(args) should be 1")
+		}
+		re, ok := UnwrapReferenceValue(args[0]).(*Regex)
+		if !ok {
+			return newError("native function regex.pattern: args[0] should be Regex")
+		}
+		return &String{Value: []rune(re.Regexp.String())}
+	}},
+}
+
+func newRegexModule() *Hash {
+	members := map[string]Object{
+		// regex("a+") compiles a regular expression
+		"@()": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 1 {
+				if arr, ok := UnwrapReferenceValue(args[0]).(*Array); ok {
+					args = arr.Elements
+				}
+			}
+			if len(args) != 1 {
+				return newError("native function regex: len(args) should be 1")
+			}
+			return compileRegex("regex", args)
+		}},
+		"compile": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function regex.compile: len(args) should be 1")
+			}
+			return compileRegex("regex.compile", args)
+		}},
+		"quote": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function regex.quote: len(args) should be 1")
+			}
+			str, err := stringArg("regex.quote", args, 0)
+			if err != nil {
+				return err
+			}
+			return &String{Value: []rune(regexp.QuoteMeta(string(str)))}
+		}},
+	}
+	for name, fn := range regexMethods {
+		members[name] = fn
+	}
+	return newModule(members)
+}
@@ -0,0 +1,165 @@
+package evaluator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Capability is a kind of access to the world outside the interpreter.
+type Capability string
+
+const (
+	CapRead  Capability = "read"
+	CapWrite Capability = "write"
+	CapEnv   Capability = "env"
+	CapExec  Capability = "exec"
+	CapNet   Capability = "net"
+	CapFFI   Capability = "ffi"
+)
+
+var capabilities = map[Capability]bool{CapRead: true, CapWrite: true, CapEnv: true, CapExec: true, CapNet: true, CapFFI: true}
+
+// Sandbox decides what scripts may do outside the interpreter. Every native
+// touching the outside world asks it first, C calls included, since they can
+// do anything the other capabilities deny. Capabilities can be dropped and
+// the file system can be confined, but neither can be undone, so a script can
+// give up what it does not need before running code it does not trust.
+type Sandbox struct {
+	denied map[Capability]bool
+	root   string
+}
+
+func NewSandbox() *Sandbox {
+	return &Sandbox{denied: map[Capability]bool{}}
+}
+
+// DefaultSandbox is used by the natives, it allows everything until told not to.
+var DefaultSandbox = NewSandbox()
+
+// Drop denies caps, it drops nothing when any of them is unknown.
+func (s *Sandbox) Drop(caps ...Capability) error {
+	for _, c := range caps {
+		if !capabilities[c] {
+			return fmt.Errorf("unknown capability %s", c)
+		}
+	}
+	for _, c := range caps {
+		s.denied[c] = true
+	}
+	return nil
+}
+
+// Allowed is false for unknown capabilities.
+func (s *Sandbox) Allowed(c Capability) bool {
+	return capabilities[c] && !s.denied[c]
+}
+
+// Confine limits the file system to root, a sandbox already confined can only
+// be confined to a directory inside its root.
+func (s *Sandbox) Confine(root string) error {
+	abs, err := resolvePath(root)
+	if err != nil {
+		return err
+	}
+	if s.root != "" && !insideRoot(s.root, abs) {
+		return os.ErrPermission
+	}
+	s.root = abs
+	return nil
+}
+
+func (s *Sandbox) Check(name string, c Capability) *Err {
+	if s.denied[c] {
+		return newError("native function %s: permission denied: %s", name, c)
+	}
+	return nil
+}
+
+// CheckPath checks the capability c on path, and returns the path to use.
+func (s *Sandbox) CheckPath(name string, c Capability, path string) (string, *Err) {
+	if err := s.Check(name, c); err != nil {
+		return "", err
+	}
+	if s.root == "" {
+		return path, nil
+	}
+	abs, err := resolvePath(path)
+	if err != nil {
+		return "", newError("native function %s: %s", name, err.Error())
+	}
+	if !insideRoot(s.root, abs) {
+		return "", newError("native function %s: permission denied: %s is outside %s", name, path, s.root)
+	}
+	return abs, nil
+}
+
+// resolvePath makes path absolute and follows the symbolic links of the part
+// of it that exists.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if dir == filepath.Dir(dir) {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+func insideRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func newSandboxModule() *Hash {
+	return newModule(map[string]Object{
+		"drop": &Native{Fn: func(env *Environment, args []Object) Object {
+			caps := make([]Capability, len(args))
+			for i := range args {
+				c, err := stringArg("sandbox.drop", args, i)
+				if err != nil {
+					return err
+				}
+				caps[i] = Capability(c)
+			}
+			if e := DefaultSandbox.Drop(caps...); e != nil {
+				return newError("native function sandbox.drop: %s", e.Error())
+			}
+			return VoidObj
+		}},
+		"allowed": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function sandbox.allowed: len(args) should be 1")
+			}
+			c, err := stringArg("sandbox.allowed", args, 0)
+			if err != nil {
+				return err
+			}
+			if !capabilities[Capability(c)] {
+				return newError("native function sandbox.allowed: unknown capability %s", string(c))
+			}
+			return nativeBoolToBooleanObject(DefaultSandbox.Allowed(Capability(c)))
+		}},
+		"confine": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function sandbox.confine: len(args) should be 1")
+			}
+			root, err := stringArg("sandbox.confine", args, 0)
+			if err != nil {
+				return err
+			}
+			if e := DefaultSandbox.Confine(string(root)); e != nil {
+				return newError("native function sandbox.confine: %s", e.Error())
+			}
+			return VoidObj
+		}},
+	})
+}
@@ -0,0 +1,277 @@
+package evaluator
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStringLen bounds the length of the Strings made by repeat, padLeft and
+// padRight.
+const maxStringLen = 1 << 28
+
+func stringArg(name string, args []Object, i int) ([]rune, Object) {
+	if i >= len(args) {
+		return nil, newError("native function %s: args[%d] should be String", name, i)
+	}
+	if letter, ok := UnwrapReferenceValue(args[i]).(Letter); ok {
+		return []rune(letter.LetterObj()), nil
+	}
+	return nil, newError("native function %s: args[%d] should be String", name, i)
+}
+
+func integerArg(name string, args []Object, i int) (int64, Object) {
+	if i >= len(args) {
+		return 0, newError("native function %s: args[%d] should be Integer", name, i)
+	}
+	if integer, ok := UnwrapReferenceValue(args[i]).(*Integer); ok && integer.Big == nil {
+		return integer.Value, nil
+	}
+	return 0, newError("native function %s: args[%d] should be Integer", name, i)
+}
+
+func stringsToArray(strs []string) *Array {
+	elements := make([]Object, len(strs))
+	for i, str := range strs {
+		elements[i] = &String{Value: []rune(str)}
+	}
+	return &Array{Elements: elements, Xvalue: true}
+}
+
+func runeIndex(str string, byteIndex int) int64 {
+	if byteIndex < 0 {
+		return -1
+	}
+	return int64(utf8.RuneCountInString(str[:byteIndex]))
+}
+
+func newStringNative(name string, min, max int, fn func(str []rune, args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function %s: len(args) should be %d", name, min)
+			}
+			return newError("native function %s: len(args) should be %d to %d", name, min, max)
+		}
+		str, err := stringArg(name, args, 0)
+		if err != nil {
+			return err
+		}
+		return fn(str, args)
+	}}
+}
+
+// stringNatives are registered in SharedEnv, and can be called as methods of
+// String, e.g. "a,b".split(",") is split("a,b", ",").
+var stringNatives map[string]*Native
+
+func newStringNatives() map[string]*Native {
+	return map[string]*Native{
+		"split": newStringNative("split", 1, 3, func(str []rune, args []Object) Object {
+			if len(args) == 1 {
+				return stringsToArray(strings.Fields(string(str)))
+			}
+			sep, err := stringArg("split", args, 1)
+			if err != nil {
+				return err
+			}
+			n := int64(-1)
+			if len(args) == 3 {
+				if n, err = integerArg("split", args, 2); err != nil {
+					return err
+				}
+			}
+			return stringsToArray(strings.SplitN(string(str), string(sep), int(n)))
+		}),
+		"join": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function join: len(args) should be 1 or 2")
+			}
+			arr, ok := UnwrapReferenceValue(args[0]).(*Array)
+			if !ok {
+				return newError("native function join: args[0] should be Array")
+			}
+			var sep []rune
+			if len(args) == 2 {
+				var err Object
+				if sep, err = stringArg("join", args, 1); err != nil {
+					return err
+				}
+			}
+			var strs []string
+			for i, e := range arr.Elements {
+				str := toString(UnwrapReferenceValue(e), env)
+				if isError(str) {
+					return str
+				}
+				s, ok := str.(*String)
+				if !ok {
+					return newError("native function join: element %d should give a String", i)
+				}
+				strs = append(strs, string(s.Value))
+			}
+			return &String{Value: []rune(strings.Join(strs, string(sep)))}
+		}},
+		"trim": newStringNative("trim", 1, 2, func(str []rune, args []Object) Object {
+			if len(args) == 1 {
+				return &String{Value: []rune(strings.TrimSpace(string(str)))}
+			}
+			cutset, err := stringArg("trim", args, 1)
+			if err != nil {
+				return err
+			}
+			return &String{Value: []rune(strings.Trim(string(str), string(cutset)))}
+		}),
+		"trimLeft": newStringNative("trimLeft", 1, 2, func(str []rune, args []Object) Object {
+			cutset := []rune(" \t\n\r\v\f")
+			if len(args) == 2 {
+				var err Object
+				if cutset, err = stringArg("trimLeft", args, 1); err != nil {
+					return err
+				}
+			}
+			return &String{Value: []rune(strings.TrimLeft(string(str), string(cutset)))}
+		}),
+		"trimRight": newStringNative("trimRight", 1, 2, func(str []rune, args []Object) Object {
+			cutset := []rune(" \t\n\r\v\f")
+			if len(args) == 2 {
+				var err Object
+				if cutset, err = stringArg("trimRight", args, 1); err != nil {
+					return err
+				}
+			}
+			return &String{Value: []rune(strings.TrimRight(string(str), string(cutset)))}
+		}),
+		"find": newStringNative("find", 2, 3, func(str []rune, args []Object) Object {
+			sub, err := stringArg("find", args, 1)
+			if err != nil {
+				return err
+			}
+			start := int64(0)
+			if len(args) == 3 {
+				if start, err = integerArg("find", args, 2); err != nil {
+					return err
+				}
+				if start < 0 || start > int64(len(str)) {
+					return &Integer{Value: -1}
+				}
+			}
+			rest := string(str[start:])
+			index := runeIndex(rest, strings.Index(rest, string(sub)))
+			if index < 0 {
+				return &Integer{Value: -1}
+			}
+			return &Integer{Value: start + index}
+		}),
+		"findLast": newStringNative("findLast", 2, 2, func(str []rune, args []Object) Object {
+			sub, err := stringArg("findLast", args, 1)
+			if err != nil {
+				return err
+			}
+			return &Integer{Value: runeIndex(string(str), strings.LastIndex(string(str), string(sub)))}
+		}),
+		"count": newStringNative("count", 2, 2, func(str []rune, args []Object) Object {
+			sub, err := stringArg("count", args, 1)
+			if err != nil {
+				return err
+			}
+			return &Integer{Value: int64(strings.Count(string(str), string(sub)))}
+		}),
+		"replace": newStringNative("replace", 3, 4, func(str []rune, args []Object) Object {
+			old, err := stringArg("replace", args, 1)
+			if err != nil {
+				return err
+			}
+			replacement, err := stringArg("replace", args, 2)
+			if err != nil {
+				return err
+			}
+			n := int64(-1)
+			if len(args) == 4 {
+				if n, err = integerArg("replace", args, 3); err != nil {
+					return err
+				}
+			}
+			return &String{Value: []rune(strings.Replace(string(str), string(old), string(replacement), int(n)))}
+		}),
+		"upper": newStringNative("upper", 1, 1, func(str []rune, args []Object) Object {
+			return &String{Value: []rune(strings.ToUpper(string(str)))}
+		}),
+		"lower": newStringNative("lower", 1, 1, func(str []rune, args []Object) Object {
+			return &String{Value: []rune(strings.ToLower(string(str)))}
+		}),
+		"startsWith": newStringNative("startsWith", 2, 2, func(str []rune, args []Object) Object {
+			prefix, err := stringArg("startsWith", args, 1)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasPrefix(string(str), string(prefix)))
+		}),
+		"endsWith": newStringNative("endsWith", 2, 2, func(str []rune, args []Object) Object {
+			suffix, err := stringArg("endsWith", args, 1)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasSuffix(string(str), string(suffix)))
+		}),
+		"repeat": newStringNative("repeat", 2, 2, func(str []rune, args []Object) Object {
+			n, err := integerArg("repeat", args, 1)
+			if err != nil {
+				return err
+			}
+			if n < 0 {
+				return newError("native function repeat: args[1] should be non-negative")
+			}
+			if len(str) > 0 && n > maxStringLen/int64(len(str)) {
+				return newError("native function repeat: result should be at most %d characters", maxStringLen)
+			}
+			return &String{Value: []rune(strings.Repeat(string(str), int(n)))}
+		}),
+		"padLeft": newStringNative("padLeft", 2, 3, func(str []rune, args []Object) Object {
+			return padString("padLeft", str, args, true)
+		}),
+		"padRight": newStringNative("padRight", 2, 3, func(str []rune, args []Object) Object {
+			return padString("padRight", str, args, false)
+		}),
+		"reverse": newStringNative("reverse", 1, 1, func(str []rune, args []Object) Object {
+			runes := make([]rune, len(str))
+			for i, c := range str {
+				runes[len(str)-1-i] = c
+			}
+			return &String{Value: runes}
+		}),
+		"chars": newStringNative("chars", 1, 1, func(str []rune, args []Object) Object {
+			elements := make([]Object, len(str))
+			for i, c := range str {
+				elements[i] = &Character{Value: c}
+			}
+			return &Array{Elements: elements, Xvalue: true}
+		}),
+	}
+}
+
+func padString(name string, str []rune, args []Object, left bool) Object {
+	width, err := integerArg(name, args, 1)
+	if err != nil {
+		return err
+	}
+	if width > maxStringLen {
+		return newError("native function %s: args[1] should be at most %d", name, maxStringLen)
+	}
+	pad := []rune(" ")
+	if len(args) == 3 {
+		if pad, err = stringArg(name, args, 2); err != nil {
+			return err
+		}
+		if len(pad) == 0 {
+			return newError("native function %s: args[2] should not be empty", name)
+		}
+	}
+	var padding []rune
+	for int64(len(str)+len(padding)) < width {
+		padding = append(padding, pad[len(padding)%len(pad)])
+	}
+	if left {
+		return &String{Value: append(padding, str...)}
+	}
+	return &String{Value: append(append([]rune{}, str...), padding...)}
+}
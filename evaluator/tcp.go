@@ -0,0 +1,330 @@
+package evaluator
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+func newSocket(conn net.Conn) *Socket {
+	return &Socket{
+		Conn:   conn,
+		Reader: bufio.NewReader(conn),
+		Addr:   conn.LocalAddr().String(),
+		Remote: conn.RemoteAddr().String(),
+	}
+}
+
+// maxSocketRead bounds what socket.readBytes(n) reads at a time, it only gives
+// what has arrived anyway.
+const maxSocketRead = 64 << 10
+
+func socketError(name string, err error) Object {
+	return newError("native function socket.%s: %s", name, err.Error())
+}
+
+// newSocketNative checks that args[0] is an open Socket, a listener when
+// listener is true, and a connection otherwise.
+func newSocketNative(name string, min, max int, listener bool, fn func(s *Socket, args []Object, env *Environment) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function socket.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function socket.%s: len(args) should be %d to %d", name, min, max)
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Socket)
+		if !ok {
+			return newError("native function socket.%s: args[0] should be Socket", name)
+		}
+		if s.Conn == nil && s.Listener == nil {
+			return newError("native function socket.%s: socket already closed", name)
+		}
+		if listener && s.Listener == nil {
+			return newError("native function socket.%s: socket is not a listener", name)
+		}
+		if !listener && s.Listener != nil {
+			return newError("native function socket.%s: socket is a listener", name)
+		}
+		return fn(s, args, env)
+	}}
+}
+
+// deadlineArg gives the time seconds from now, and no deadline for 0.
+func deadlineArg(name string, args []Object, i int) (time.Time, Object) {
+	seconds, err := floatArg(name, args, i)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if seconds == 0 {
+		return time.Time{}, nil
+	}
+	return time.Now().Add(secondsToDuration(seconds)), nil
+}
+
+func newDeadlineNative(name string, set func(conn net.Conn, t time.Time) error) *Native {
+	return newSocketNative(name, 2, 2, false, func(s *Socket, args []Object, env *Environment) Object {
+		t, err := deadlineArg("socket."+name, args, 1)
+		if err != nil {
+			return err
+		}
+		if e := set(s.Conn, t); e != nil {
+			return socketError(name, e)
+		}
+		return VoidObj
+	})
+}
+
+// socketMethods are the methods of Socket, which is args[0]. The methods
+// which wait release the interpreter, so handlers of tcp.serve and http.listen
+// run meanwhile, and may close the socket, so they take its connection before
+// waiting.
+var socketMethods = map[string]*Native{
+	"accept": newSocketNative("accept", 1, 1, true, func(s *Socket, args []Object, env *Environment) Object {
+		listener := s.Listener
+		var conn net.Conn
+		var e error
+		blocking(func() { conn, e = listener.Accept() })
+		if e != nil {
+			return socketError("accept", e)
+		}
+		return newSocket(conn)
+	}),
+	// serve calls fn with each connection in the background, and closes the
+	// connection when fn returns. The first error of fn is given by wait.
+	"serve": newSocketNative("serve", 2, 2, true, func(s *Socket, args []Object, env *Environment) Object {
+		fn, err := functorArg("socket.serve", args, 1)
+		if err != nil {
+			return err
+		}
+		if s.Done != nil {
+			return newError("native function socket.serve: socket already served")
+		}
+		listener, done := s.Listener, make(chan struct{})
+		s.Done = done
+		go func() {
+			defer close(done)
+			for {
+				conn, e := listener.Accept()
+				if e != nil {
+					return
+				}
+				go inInterpreter(func() {
+					socket := newSocket(conn)
+					if result := callFunctor(fn, []Object{socket}, env); isError(result) && s.Err == nil {
+						s.Err = result
+					}
+					if socket.Conn != nil {
+						_ = socket.Conn.Close()
+					}
+				})
+			}
+		}()
+		return VoidObj
+	}),
+	"read": newSocketNative("read", 1, 1, false, func(s *Socket, args []Object, env *Environment) Object {
+		r := s.Reader
+		var data []byte
+		var e error
+		blocking(func() { data, e = ioutil.ReadAll(r) })
+		if e != nil {
+			return socketError("read", e)
+		}
+		return &String{Value: []rune(string(data))}
+	}),
+	// readBytes() reads everything until the other side closes, readBytes(n)
+	// reads what has arrived, at most n bytes, and gives empty Bytes at the end
+	"readBytes": newSocketNative("readBytes", 1, 2, false, func(s *Socket, args []Object, env *Environment) Object {
+		r := s.Reader
+		if len(args) == 1 {
+			var data []byte
+			var e error
+			blocking(func() { data, e = ioutil.ReadAll(r) })
+			if e != nil {
+				return socketError("readBytes", e)
+			}
+			return &Bytes{Value: data}
+		}
+		n, err := integerArg("socket.readBytes", args, 1)
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return newError("native function socket.readBytes: args[1] should be positive")
+		}
+		if n > maxSocketRead {
+			n = maxSocketRead
+		}
+		data := make([]byte, n)
+		var read int
+		var e error
+		blocking(func() { read, e = r.Read(data) })
+		if e != nil && e != io.EOF {
+			return socketError("readBytes", e)
+		}
+		return &Bytes{Value: data[:read]}
+	}),
+	"readLine": newSocketNative("readLine", 1, 1, false, func(s *Socket, args []Object, env *Environment) Object {
+		r := s.Reader
+		var line Object
+		var e error
+		blocking(func() { line, e = readLine(r) })
+		if e != nil {
+			return socketError("readLine", e)
+		}
+		return line
+	}),
+	"write": newSocketNative("write", 2, 2, false, func(s *Socket, args []Object, env *Environment) Object {
+		data, err := dataArg("socket.write", args, 1)
+		if err != nil {
+			return err
+		}
+		conn := s.Conn
+		var e error
+		blocking(func() { _, e = conn.Write(data) })
+		if e != nil {
+			return socketError("write", e)
+		}
+		return VoidObj
+	}),
+	// closeWrite tells the other side that nothing more will be written
+	"closeWrite": newSocketNative("closeWrite", 1, 1, false, func(s *Socket, args []Object, env *Environment) Object {
+		conn, ok := s.Conn.(*net.TCPConn)
+		if !ok {
+			return newError("native function socket.closeWrite: not a TCP connection")
+		}
+		if e := conn.CloseWrite(); e != nil {
+			return socketError("closeWrite", e)
+		}
+		return VoidObj
+	}),
+	"setDeadline":      newDeadlineNative("setDeadline", net.Conn.SetDeadline),
+	"setReadDeadline":  newDeadlineNative("setReadDeadline", net.Conn.SetReadDeadline),
+	"setWriteDeadline": newDeadlineNative("setWriteDeadline", net.Conn.SetWriteDeadline),
+	"setAcceptDeadline": newSocketNative("setAcceptDeadline", 2, 2, true, func(s *Socket, args []Object, env *Environment) Object {
+		t, err := deadlineArg("socket.setAcceptDeadline", args, 1)
+		if err != nil {
+			return err
+		}
+		if e := s.Listener.SetDeadline(t); e != nil {
+			return socketError("setAcceptDeadline", e)
+		}
+		return VoidObj
+	}),
+	// wait waits until a listener given to serve is closed, and gives the
+	// first error of its handlers
+	"wait": &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function socket.wait: len(args) should be 1")
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Socket)
+		if !ok || s.Done == nil {
+			return newError("native function socket.wait: args[0] should be a served Socket")
+		}
+		done := s.Done
+		blocking(func() { <-done })
+		if s.Err != nil {
+			return s.Err
+		}
+		return VoidObj
+	}},
+	"addr": &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function socket.addr: len(args) should be 1")
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Socket)
+		if !ok {
+			return newError("native function socket.addr: args[0] should be Socket")
+		}
+		return &String{Value: []rune(s.Addr)}
+	}},
+	"remoteAddr": &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function socket.remoteAddr: len(args) should be 1")
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Socket)
+		if !ok || s.Remote == "" {
+			return newError("native function socket.remoteAddr: args[0] should be a connected Socket")
+		}
+		return &String{Value: []rune(s.Remote)}
+	}},
+	"close": &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) != 1 {
+			return newError("native function socket.close: len(args) should be 1")
+		}
+		s, ok := UnwrapReferenceValue(args[0]).(*Socket)
+		if !ok {
+			return newError("native function socket.close: args[0] should be Socket")
+		}
+		var e error
+		if s.Conn != nil {
+			e = s.Conn.Close()
+		} else if s.Listener != nil {
+			e = s.Listener.Close()
+		}
+		s.Conn, s.Reader, s.Listener = nil, nil, nil
+		if e != nil {
+			return socketError("close", e)
+		}
+		return VoidObj
+	}},
+}
+
+func newTcpModule() *Hash {
+	return newModule(map[string]Object{
+		// tcp.listen(addr) listens at addr, 127.0.0.1 with a free port when
+		// addr is omitted
+		"listen": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) > 1 {
+				return newError("native function tcp.listen: len(args) should be 0 to 1")
+			}
+			if err := DefaultSandbox.Check("tcp.listen", CapNet); err != nil {
+				return err
+			}
+			addr := "127.0.0.1:0"
+			if len(args) == 1 {
+				str, err := stringArg("tcp.listen", args, 0)
+				if err != nil {
+					return err
+				}
+				addr = string(str)
+			}
+			listener, e := net.Listen("tcp", addr)
+			if e != nil {
+				return newError("native function tcp.listen: %s", e.Error())
+			}
+			return &Socket{Listener: listener.(*net.TCPListener), Addr: listener.Addr().String()}
+		}},
+		// tcp.connect(addr, timeout) connects to addr, waiting at most timeout
+		// seconds when it is given
+		"connect": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("native function tcp.connect: len(args) should be 1 to 2")
+			}
+			if err := DefaultSandbox.Check("tcp.connect", CapNet); err != nil {
+				return err
+			}
+			addr, err := stringArg("tcp.connect", args, 0)
+			if err != nil {
+				return err
+			}
+			var timeout time.Duration
+			if len(args) == 2 {
+				seconds, err := floatArg("tcp.connect", args, 1)
+				if err != nil {
+					return err
+				}
+				timeout = secondsToDuration(seconds)
+			}
+			var conn net.Conn
+			var e error
+			blocking(func() { conn, e = net.DialTimeout("tcp", string(addr), timeout) })
+			if e != nil {
+				return newError("native function tcp.connect: %s", e.Error())
+			}
+			return newSocket(conn)
+		}},
+	})
+}
@@ -0,0 +1,255 @@
+package evaluator
+
+import (
+	"math"
+	"time"
+	_ "time/tzdata"
+)
+
+var monotonicStart = time.Now()
+
+var timeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// timeLayout resolves the name of a common layout, and otherwise takes layout
+// as a Go layout, e.g. "2006-01-02 15:04".
+func timeLayout(layout string) string {
+	if l, ok := timeLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+func zoneArg(name string, args []Object, i int) (*time.Location, Object) {
+	zone, err := stringArg(name, args, i)
+	if err != nil {
+		return nil, err
+	}
+	loc, e := time.LoadLocation(string(zone))
+	if e != nil {
+		return nil, newError("native function %s: unknown time zone %s", name, string(zone))
+	}
+	return loc, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
+
+func newTimeNative(name string, min, max int, fn func(t time.Time, args []Object) Object) *Native {
+	return &Native{Fn: func(env *Environment, args []Object) Object {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return newError("native function time.%s: len(args) should be %d", name, min)
+			}
+			return newError("native function time.%s: len(args) should be %d to %d", name, min, max)
+		}
+		t, ok := UnwrapReferenceValue(args[0]).(*Time)
+		if !ok {
+			return newError("native function time.%s: args[0] should be Time", name)
+		}
+		return fn(t.Value, args)
+	}}
+}
+
+func newTimeFieldNative(name string, field func(t time.Time) int) *Native {
+	return newTimeNative(name, 1, 1, func(t time.Time, args []Object) Object {
+		return &Integer{Value: int64(field(t))}
+	})
+}
+
+func newTimeCompareNative(operator string, cmp func(a, b time.Time) bool) *Native {
+	return newTimeNative("@"+operator, 2, 2, func(t time.Time, args []Object) Object {
+		other, ok := UnwrapReferenceValue(args[1]).(*Time)
+		if !ok && (operator == "==" || operator == "!=") {
+			return nativeBoolToBooleanObject(operator == "!=")
+		}
+		if !ok {
+			return newError("type mismatch: Time %s %s", operator, UnwrapReferenceValue(args[1]).Type())
+		}
+		return nativeBoolToBooleanObject(cmp(t, other.Value))
+	})
+}
+
+// timeMethods are the methods of Time, the time is args[0]. Operators follow
+// the "@" protocol of Hash: Time ± seconds is a Time, and Time - Time is the
+// seconds between them as a Float.
+var timeMethods = map[string]*Native{
+	"format": newTimeNative("format", 2, 2, func(t time.Time, args []Object) Object {
+		layout, err := stringArg("time.format", args, 1)
+		if err != nil {
+			return err
+		}
+		return &String{Value: []rune(t.Format(timeLayout(string(layout))))}
+	}),
+	"inZone": newTimeNative("inZone", 2, 2, func(t time.Time, args []Object) Object {
+		loc, err := zoneArg("time.inZone", args, 1)
+		if err != nil {
+			return err
+		}
+		return &Time{Value: t.In(loc)}
+	}),
+	"zone": newTimeNative("zone", 1, 1, func(t time.Time, args []Object) Object {
+		name, _ := t.Zone()
+		return &String{Value: []rune(name)}
+	}),
+	"unix": newTimeNative("unix", 1, 1, func(t time.Time, args []Object) Object {
+		return &Float{Value: float64(t.UnixNano()) / float64(time.Second)}
+	}),
+	"weekday": newTimeNative("weekday", 1, 1, func(t time.Time, args []Object) Object {
+		return &String{Value: []rune(t.Weekday().String())}
+	}),
+	"year":       newTimeFieldNative("year", time.Time.Year),
+	"month":      newTimeFieldNative("month", func(t time.Time) int { return int(t.Month()) }),
+	"day":        newTimeFieldNative("day", time.Time.Day),
+	"hour":       newTimeFieldNative("hour", time.Time.Hour),
+	"minute":     newTimeFieldNative("minute", time.Time.Minute),
+	"second":     newTimeFieldNative("second", time.Time.Second),
+	"nanosecond": newTimeFieldNative("nanosecond", time.Time.Nanosecond),
+	"yearDay":    newTimeFieldNative("yearDay", time.Time.YearDay),
+
+	"@+": newTimeNative("@+", 2, 2, func(t time.Time, args []Object) Object {
+		seconds, err := floatArg("time.@+", args, 1)
+		if err != nil {
+			return newError("type mismatch: Time + %s", UnwrapReferenceValue(args[1]).Type())
+		}
+		return &Time{Value: t.Add(secondsToDuration(seconds))}
+	}),
+	"@-": newTimeNative("@-", 2, 2, func(t time.Time, args []Object) Object {
+		if other, ok := UnwrapReferenceValue(args[1]).(*Time); ok {
+			return &Float{Value: t.Sub(other.Value).Seconds()}
+		}
+		seconds, err := floatArg("time.@-", args, 1)
+		if err != nil {
+			return newError("type mismatch: Time - %s", UnwrapReferenceValue(args[1]).Type())
+		}
+		return &Time{Value: t.Add(-secondsToDuration(seconds))}
+	}),
+	"@==": newTimeCompareNative("==", time.Time.Equal),
+	"@!=": newTimeCompareNative("!=", func(a, b time.Time) bool { return !a.Equal(b) }),
+	"@<":  newTimeCompareNative("<", time.Time.Before),
+	"@>":  newTimeCompareNative(">", time.Time.After),
+	"@<=": newTimeCompareNative("<=", func(a, b time.Time) bool { return !a.After(b) }),
+	"@>=": newTimeCompareNative(">=", func(a, b time.Time) bool { return !a.Before(b) }),
+}
+
+func newTimeModule() *Hash {
+	members := map[string]Object{
+		// time("2006-01-02T15:04:05Z") parses a time in RFC 3339
+		"@()": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) == 1 {
+				if arr, ok := UnwrapReferenceValue(args[0]).(*Array); ok {
+					args = arr.Elements
+				}
+			}
+			if len(args) != 1 {
+				return newError("native function time: len(args) should be 1")
+			}
+			str, err := stringArg("time", args, 0)
+			if err != nil {
+				return err
+			}
+			t, e := time.Parse(time.RFC3339Nano, string(str))
+			if e != nil {
+				return newError("native function time: %s", e.Error())
+			}
+			return &Time{Value: t}
+		}},
+		"now": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 0 {
+				return newError("native function time.now: len(args) should be 0")
+			}
+			return &Time{Value: time.Now()}
+		}},
+		"monotonic": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 0 {
+				return newError("native function time.monotonic: len(args) should be 0")
+			}
+			return &Float{Value: time.Since(monotonicStart).Seconds()}
+		}},
+		"sleep": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function time.sleep: len(args) should be 1")
+			}
+			seconds, err := floatArg("time.sleep", args, 0)
+			if err != nil {
+				return err
+			}
+			blocking(func() { time.Sleep(secondsToDuration(seconds)) })
+			return VoidObj
+		}},
+		"unix": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 1 {
+				return newError("native function time.unix: len(args) should be 1")
+			}
+			seconds, err := floatArg("time.unix", args, 0)
+			if err != nil {
+				return err
+			}
+			sec, frac := math.Modf(seconds)
+			return &Time{Value: time.Unix(int64(sec), int64(math.Round(frac*float64(time.Second)))).UTC()}
+		}},
+		"date": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) < 3 || len(args) > 8 {
+				return newError("native function time.date: len(args) should be 3 to 8")
+			}
+			var fields [7]int64
+			loc := time.UTC
+			for i := range args {
+				if i == 7 {
+					var err Object
+					if loc, err = zoneArg("time.date", args, i); err != nil {
+						return err
+					}
+					break
+				}
+				field, err := integerArg("time.date", args, i)
+				if err != nil {
+					return err
+				}
+				fields[i] = field
+			}
+			return &Time{Value: time.Date(int(fields[0]), time.Month(fields[1]), int(fields[2]),
+				int(fields[3]), int(fields[4]), int(fields[5]), int(fields[6]), loc)}
+		}},
+		"parse": &Native{Fn: func(env *Environment, args []Object) Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("native function time.parse: len(args) should be 2 or 3")
+			}
+			layout, err := stringArg("time.parse", args, 0)
+			if err != nil {
+				return err
+			}
+			str, err := stringArg("time.parse", args, 1)
+			if err != nil {
+				return err
+			}
+			loc := time.UTC
+			if len(args) == 3 {
+				if loc, err = zoneArg("time.parse", args, 2); err != nil {
+					return err
+				}
+			}
+			t, e := time.ParseInLocation(timeLayout(string(layout)), string(str), loc)
+			if e != nil {
+				return newError("native function time.parse: %s", e.Error())
+			}
+			return &Time{Value: t}
+		}},
+	}
+	for name, fn := range timeMethods {
+		if _, ok := members[name]; !ok && name[0] != '@' {
+			members[name] = fn
+		}
+	}
+	return newModule(members)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/mark07x/TLang/ast"
 	"github.com/mark07x/TLang/lexer"
 	"github.com/mark07x/TLang/token"
+	"math/big"
 	"strconv"
 )
 
@@ -48,6 +49,7 @@ var precedences = map[token.Type]int{
 	token.Dot:          Index,
 	token.And:          And,
 	token.Or:           Or,
+	token.In:           Equals,
 	token.Assign:       Assign,
 	token.PlusEq:       Assign,
 	token.MinusEq:      Assign,
@@ -67,6 +69,8 @@ type Parser struct {
 
 	curToken  token.Token
 	peekToken token.Token
+	// secondToken is the token after peekToken
+	secondToken token.Token
 
 	prefixParseFns map[token.Type]prefixParseFn
 	infixParseFns  map[token.Type]infixParseFn
@@ -114,7 +118,8 @@ func (p *Parser) noPrefixParseFnError(t token.Type) {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.peekToken = p.secondToken
+	p.secondToken = p.l.NextToken()
 }
 
 func (p *Parser) curTokenIs(t token.Type) bool {
@@ -183,6 +188,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GtEq, p.parseInfixExpression)
 	p.registerInfix(token.And, p.parseInfixExpression)
 	p.registerInfix(token.Or, p.parseInfixExpression)
+	p.registerInfix(token.In, p.parseInfixExpression)
 	p.registerInfix(token.Assign, p.parseInfixExpression)
 
 	p.registerInfix(token.Lparen, p.parseCallExpression)
@@ -204,7 +210,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.SlashEq, p.parseAssignExpression)
 	p.registerInfix(token.PercentageEq, p.parseAssignExpression)
 
-	// Read two tokens, so curToken and peekToken are both set
+	// Read three tokens, so curToken, peekToken and secondToken are all set
+	p.nextToken()
 	p.nextToken()
 	p.nextToken()
 
@@ -238,6 +245,11 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseJumpStatement()
 	case token.Del:
 		return p.parseDelStatement()
+	case token.Ident:
+		if p.isImportStatement() {
+			return p.parseImportStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -348,6 +360,96 @@ func (p *Parser) parseDelStatement() *ast.DelStatement {
 	return stmt
 }
 
+// isImportStatement tells import { ... } from "x.t" and import "x.t" as m
+// from calling import, which stays an expression. import followed by { is
+// always the statement, import takes a String, so calling it with an
+// underline function was never of use.
+func (p *Parser) isImportStatement() bool {
+	if p.curToken.Literal != "import" {
+		return false
+	}
+	if p.peekTokenIs(token.Lbrace) {
+		return true
+	}
+	if p.peekTokenIs(token.String) {
+		return p.secondToken.Type == token.Ident && p.secondToken.Literal == "as"
+	}
+	return false
+}
+
+func (p *Parser) expectPeekWord(word string) bool {
+	if p.peekTokenIs(token.Ident) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+		word, p.peekToken.Literal)
+	p.errors = append(p.errors, msg)
+	return false
+}
+
+func (p *Parser) parseImportPath() *ast.StringLiteral {
+	if !p.expectPeek(token.String) {
+		return nil
+	}
+	str, ok := p.parseStringLiteral().(*ast.StringLiteral)
+	if !ok {
+		return nil
+	}
+	return str
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.String) {
+		if stmt.Path = p.parseImportPath(); stmt.Path == nil {
+			return nil
+		}
+		if !p.expectPeekWord("as") || !p.expectPeek(token.Ident) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	} else {
+		p.nextToken()
+		for !p.peekTokenIs(token.Rbrace) {
+			if !p.expectPeek(token.Ident) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			var alias *ast.Identifier
+			if p.peekTokenIs(token.Ident) && p.peekToken.Literal == "as" {
+				p.nextToken()
+				if !p.expectPeek(token.Ident) {
+					return nil
+				}
+				alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+			stmt.Aliases = append(stmt.Aliases, alias)
+			if !p.peekTokenIs(token.Rbrace) && !p.expectPeek(token.Comma) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if len(stmt.Names) == 0 {
+			p.errors = append(p.errors, "import statement should have names")
+			return nil
+		}
+		if !p.expectPeekWord("from") {
+			return nil
+		}
+		if stmt.Path = p.parseImportPath(); stmt.Path == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.Semicolon) {
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -384,6 +486,11 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 func (p *Parser) parseNumberLiteral() ast.Expression {
 	valueInt, errInt := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errInt != nil {
+		if valueBig, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			return &ast.IntegerLiteral{Token: p.curToken, Big: valueBig}
+		}
+	}
 	valueFloat, errFloat := strconv.ParseFloat(p.curToken.Literal, 64)
 	if errInt != nil && errFloat != nil {
 		msg := fmt.Sprintf("could not parse %q as integer or float", p.curToken.Literal)
@@ -455,6 +562,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(Lowest)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.Rbrace) && !p.expectPeek(token.Comma) {
 			return nil
@@ -712,10 +820,69 @@ func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
-	exp.Indexes = p.parseExpressionList(token.Rbracket)
+	exp.Indexes = p.parseIndexList()
 	return exp
 }
 
+func (p *Parser) parseIndexList() []ast.Expression {
+	var list []ast.Expression
+
+	if p.peekTokenIs(token.Rbracket) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseIndex())
+
+	for p.peekTokenIs(token.Comma) {
+		p.nextToken()
+		p.nextToken()
+		if p.curTokenIs(token.Rbracket) {
+			return list
+		}
+		list = append(list, p.parseIndex())
+	}
+
+	if !p.expectPeek(token.Rbracket) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseIndex() ast.Expression {
+	var start ast.Expression
+	if !p.curTokenIs(token.Colon) {
+		start = p.parseExpression(Lowest)
+		if !p.peekTokenIs(token.Colon) {
+			return start
+		}
+		p.nextToken()
+	}
+
+	slice := &ast.SliceExpression{Token: p.curToken, Start: start}
+
+	if !p.peekTokenIs(token.Colon) && !p.peekSliceEnd() {
+		p.nextToken()
+		slice.End = p.parseExpression(Lowest)
+	}
+
+	if p.peekTokenIs(token.Colon) {
+		p.nextToken()
+		if !p.peekSliceEnd() {
+			p.nextToken()
+			slice.Step = p.parseExpression(Lowest)
+		}
+	}
+
+	return slice
+}
+
+func (p *Parser) peekSliceEnd() bool {
+	return p.peekTokenIs(token.Rbracket) || p.peekTokenIs(token.Comma)
+}
+
 func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	var list []ast.Expression
 
@@ -161,6 +161,65 @@ jump;
 	}
 }
 
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "x.t" as m;`, `import "x.t" as m;`},
+		{`import { a } from "x.t";`, `import { a } from "x.t";`},
+		{`import { a, b as c, } from "../x";`, `import { a, b as c } from "../x";`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	calls := []string{`import "x.t";`, `import("x.t");`, `let m = import "x.t";`}
+	for _, input := range calls {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if _, ok := program.Statements[0].(*ast.ImportStatement); ok {
+			t.Errorf("%s should not be an import statement", input)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import {} from "x.t";`, "import statement should have names"},
+		{`import { a } "x.t";`, "expected next token to be from, got x.t instead"},
+		{`import "x.t" as;`, "expected next token to be Ident, got ; instead"},
+	}
+
+	for _, tt := range errTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("input=%s, expected error %q, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -239,6 +298,17 @@ func TestNumberLiteralExpression(t *testing.T) {
 		t.Errorf("literal.TokenLiteral not %s. got=%s", "3",
 			literalInt.TokenLiteral())
 	}
+
+	p = New(lexer.New("99999999999999999999;"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	literalBig, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", program.Statements[0])
+	}
+	if literalBig.Big == nil || literalBig.Big.String() != "99999999999999999999" {
+		t.Errorf("literal.Big not %s. got=%s", "99999999999999999999", literalBig.Big)
+	}
 }
 
 func TestParsingPrefixExpressions(t *testing.T) {
@@ -436,6 +506,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * b / c;",
 			"((a * b) / c);",
 		},
+		{
+			"a + 1 in b and c;",
+			"(((a + 1) in b) and c);",
+		},
 		{
 			"a + b / c;",
 			"(a + (b / c));",
@@ -985,6 +1059,34 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2];", "(a[1:2])"},
+		{"a[:2];", "(a[:2])"},
+		{"a[1:];", "(a[1:])"},
+		{"a[:];", "(a[:])"},
+		{"a[::2];", "(a[::2])"},
+		{"a[::-1];", "(a[::(-1)])"},
+		{"a[i + 1:-1:2];", "(a[(i + 1):(-1):2])"},
+		{"a[1:2] = [3];", "(a[1:2]) = [3]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3};`
 
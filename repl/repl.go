@@ -13,7 +13,7 @@ const PROMPT = "T> "
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
-	env := evaluator.SharedEnv.NewEnclosedEnvironment()
+	env := evaluator.NewScriptEnvironment()
 
 	for {
 		fmt.Printf(PROMPT)